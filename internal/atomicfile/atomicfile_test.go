@@ -0,0 +1,66 @@
+package atomicfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFileReplacesWithoutLeftovers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "highlights.json")
+	for _, content := range []string{"old", "new"} {
+		if err := WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", content, err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("expected the new content, got %q (%v)", data, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary files left, got %v", entries)
+	}
+
+	if err := WriteFile(filepath.Join(dir, "missing", "a.cfg"), []byte("x"), 0o644); err == nil {
+		t.Fatalf("expected an error for a missing directory")
+	}
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "library", ".lock")
+	lock, err := Acquire(context.Background(), path, 0)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if _, err := Acquire(context.Background(), path, 0); err == nil || !strings.Contains(err.Error(), "by pid") {
+		t.Fatalf("expected the held lock to be reported, got %v", err)
+	}
+
+	released := make(chan error, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		released <- lock.Release()
+	}()
+	second, err := Acquire(context.Background(), path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	if err := <-released; err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package bootstrap
+
+import (
+	"errors"
+	"flag"
+	"slices"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/captions"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// addCaptionTemplateFlag registers the caption wording shared by the
+// subtitle export and the ffmpeg captions.
+func addCaptionTemplateFlag(flags *flag.FlagSet, captioner *captions.Captioner) {
+	flags.Func("caption-template", "caption as [type=]template (repeatable; no type = every type); placeholders: {"+strings.Join(captions.Placeholders(), "},{")+"}, [text] is dropped when a placeholder in it is empty", func(v string) error {
+		return parseCaptionTemplate(captioner, v)
+	})
+}
+
+// parseCaptionTemplate sets the captioner's template, or the template of one
+// highlight type when raw starts with a known type and "=".
+func parseCaptionTemplate(captioner *captions.Captioner, raw string) error {
+	typ, text := model.HighlightType(""), raw
+	if idx := strings.Index(raw, "="); idx >= 0 {
+		name := model.HighlightType(strings.ToLower(strings.TrimSpace(raw[:idx])))
+		if slices.Contains(model.AllHighlightTypes(), name) {
+			typ, text = name, raw[idx+1:]
+		}
+	}
+	template, err := captions.ParseTemplate(strings.TrimSpace(text))
+	if err != nil {
+		return err
+	}
+	if typ == "" {
+		captioner.Template = template
+		return nil
+	}
+	if captioner.Templates == nil {
+		captioner.Templates = make(map[model.HighlightType]captions.Template)
+	}
+	captioner.Templates[typ] = template
+	return nil
+}
+
+func validateCaptioner(captioner captions.Captioner) error {
+	if captioner.Lead < 0 {
+		return errors.New("caption-lead must be >= 0")
+	}
+	if captioner.Hold <= 0 {
+		return errors.New("caption-hold must be > 0")
+	}
+	return nil
+}
+
+// captionFiles writes the subtitles of the target's manifest in each
+// format. Recorders that write no manifest get none.
+func captionFiles(target hlae.Target, files []hlae.File, formats []captions.Format, captioner *captions.Captioner) ([]hlae.File, error) {
+	if len(formats) == 0 {
+		return nil, nil
+	}
+	manifest, ok, err := targetManifest(target, files)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return captioner.Files(manifest, target.Path, formats), nil
+}
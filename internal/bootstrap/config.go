@@ -5,91 +5,257 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/eSheikh/cs2-demo-highlighter/internal/captions"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/demo"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/ffmpeg"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/repository"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/service"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/timeline"
+)
+
+var (
+	hexColorPattern = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+	presetPattern   = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
 )
 
 type Config struct {
-	DemoPath   string
-	SteamID    string
-	OutputPath string
-	Types      model.Selection
-	Renders    []hlae.Target
-	HLAE       hlae.Options
+	// DemoPaths holds one demo, or several whose render targets are chained
+	// into one master script each.
+	DemoPaths   []string
+	SteamID     string
+	OutputPath  string
+	OutFormat   repository.Format
+	Types       model.Selection
+	Perspective model.Perspective
+	Streaks     []service.StreakRule
+	LowHP       int
+	Team        bool
+	Top         int
+	Renders     []hlae.Target
+	HLAE        hlae.Options
+	// Timelines lists the editing timelines exported next to the manifest
+	// of every render target.
+	Timelines []timeline.Format
+	// FFmpegScripts writes an ffmpeg post-processing script next to the
+	// manifest of every render target, cutting as FFmpeg describes.
+	FFmpegScripts bool
+	FFmpeg        ffmpeg.Builder
+	// Captions lists the subtitle formats written next to the manifest of
+	// every render target, worded by Captioner.
+	Captions  []captions.Format
+	Captioner captions.Captioner
+	// Descriptions writes a Markdown description next to the manifest of
+	// every render target, and YouTube chapters for montages.
+	Descriptions bool
+	// LockPath is a lock file held while the run writes its results and
+	// recordings, waiting up to LockWait for another run holding it.
+	LockPath string
+	LockWait time.Duration
 }
 
 func ParseConfig(args []string) (Config, error) {
+	cfg := defaultConfig()
+
+	var (
+		typesRaw       string
+		perspectiveRaw string
+		streaksRaw     string
+		outFormatRaw   string
+		render         renderFlags
+	)
+
+	flags := flag.NewFlagSet("highlighter", flag.ContinueOnError)
+	flags.Func("demo", "path to .dem file (repeat to chain several demos into each render target)", func(v string) error {
+		cfg.DemoPaths = append(cfg.DemoPaths, v)
+		return nil
+	})
+	flags.StringVar(&cfg.SteamID, "steamid", "", "steamid64 to filter kills")
+	flags.BoolVar(&cfg.Team, "team", false, "extract and rank highlights of every player (steamid not required)")
+	flags.IntVar(&cfg.Top, "top", 0, "with --team, keep only the N best-ranked highlights (0 = all)")
+	flags.StringVar(&typesRaw, "types", "", "comma-separated highlight types kept in the result (empty = all): "+strings.Join(formatNames(model.AllHighlightTypes()), ","))
+	flags.StringVar(&perspectiveRaw, "perspective", string(model.PerspectiveKills), "extract the player's kills or deaths: "+strings.Join(formatNames(model.AllPerspectives()), ","))
+	flags.StringVar(&streaksRaw, "streaks", formatStreakRules(service.DefaultStreakRules()), "kill_streak definitions as kind:min, comma-separated (kinds: rounds, kills; none disables)")
+	flags.IntVar(&cfg.LowHP, "low-hp", cfg.LowHP, "highest killer health that counts as a low_hp_kill (0 disables)")
+	flags.StringVar(&cfg.OutputPath, "out", cfg.OutputPath, "output path")
+	flags.StringVar(&outFormatRaw, "out-format", "", "output format (empty = from the --out extension): "+strings.Join(formatNames(repository.AllFormats()), ","))
+	addRenderFlags(flags, &cfg, &render)
+	addLockFlags(flags, &cfg)
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+	cfg.normalize()
+
+	selection, err := parseTypes(typesRaw)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Types = selection
+
+	if err := render.apply(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	perspective, err := parsePerspective(perspectiveRaw)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Perspective = perspective
+
+	outFormat, err := parseOutFormat(outFormatRaw)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.OutFormat = outFormat
+
+	streaks, err := parseStreakRules(streaksRaw)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Streaks = streaks
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// defaultConfig is the configuration before any flag is parsed.
+func defaultConfig() Config {
 	cwd, err := os.Getwd()
 	if err != nil {
 		cwd = "."
 	}
 	defaultOutputPath := filepath.Clean(cwd)
 
-	cfg := Config{
+	return Config{
 		OutputPath: "highlights.json",
+		LowHP:      service.NewHighlightService().LowHPThreshold,
+		FFmpeg:     *ffmpeg.NewBuilder(),
+		Captioner:  *captions.NewCaptioner(),
 		HLAE: hlae.Options{
-			FrameRate:       60,
-			OutputPath:      defaultOutputPath,
-			FFmpegPreset:    "afxFfmpegYuv420p",
-			PreRollSeconds:  3,
-			PostRollSeconds: 2,
-			KillGapSeconds:  10,
+			FrameRate:         60,
+			OutputPath:        defaultOutputPath,
+			FFmpegPreset:      "afxFfmpegYuv420p",
+			PreRollSeconds:    3,
+			PostRollSeconds:   2,
+			KillGapSeconds:    10,
+			SlowMotionSeconds: 1,
+			IntroStyle:        hlae.IntroOrbit,
+			IntroSeconds:      3,
 		},
 	}
+}
 
-	var (
-		typesRaw string
-		renders  []hlae.Target
-	)
+// renderFlags holds the raw values of the render flags until apply parses
+// them into a Config.
+type renderFlags struct {
+	replayTypes string
+	introTypes  string
+	introStyle  string
+	hud         string
+	timelines   string
+	captions    string
+	profiles    string
+	renders     []renderSpec
+}
 
-	flags := flag.NewFlagSet("highlighter", flag.ContinueOnError)
-	flags.StringVar(&cfg.DemoPath, "demo", "", "path to .dem file")
-	flags.StringVar(&cfg.SteamID, "steamid", "", "steamid64 to filter kills")
-	flags.StringVar(&typesRaw, "types", "", "comma-separated highlight types kept in the result (empty = all): "+strings.Join(highlightTypeNames(), ","))
-	flags.StringVar(&cfg.OutputPath, "out", cfg.OutputPath, "output json path")
-	flags.Func("clips", "clips render target as [types=]path.cfg (repeatable); types empty/all = every type", func(v string) error {
-		return appendRender(&renders, hlae.ModeClips, v)
+// addRenderFlags registers the render target, --hlae-* and post-processing
+// flags shared by the main run and the render command.
+func addRenderFlags(flags *flag.FlagSet, cfg *Config, raw *renderFlags) {
+	flags.Func("clips", "clips render target as [types=]path.cfg[;key=value...] (repeatable); types empty/all = every type; keys: "+strings.Join(renderOptionKeys(), ","), func(v string) error {
+		return appendRender(&raw.renders, hlae.ModeClips, v)
 	})
-	flags.Func("montage", "montage render target as [types=]path.cfg (repeatable); one continuous recording", func(v string) error {
-		return appendRender(&renders, hlae.ModeMontage, v)
+	flags.Func("montage", "montage render target as [types=]path.cfg[;key=value...] (repeatable); one continuous recording", func(v string) error {
+		return appendRender(&raw.renders, hlae.ModeMontage, v)
 	})
+	flags.StringVar(&raw.profiles, "profiles", "", "JSON file of named render profiles, used by render targets as ;profile=name")
 	flags.IntVar(&cfg.HLAE.FrameRate, "hlae-fps", cfg.HLAE.FrameRate, "recording framerate")
 	flags.StringVar(&cfg.HLAE.OutputPath, "hlae-path", cfg.HLAE.OutputPath, "output directory for mirv_streams recordings")
 	flags.StringVar(&cfg.HLAE.FFmpegPreset, "hlae-preset", cfg.HLAE.FFmpegPreset, "HLAE ffmpeg preset for mirv_streams")
 	flags.IntVar(&cfg.HLAE.PreRollSeconds, "hlae-preroll", cfg.HLAE.PreRollSeconds, "seconds added before each highlight")
 	flags.IntVar(&cfg.HLAE.PostRollSeconds, "hlae-postroll", cfg.HLAE.PostRollSeconds, "seconds added after each highlight")
 	flags.IntVar(&cfg.HLAE.KillGapSeconds, "hlae-kill-gap", cfg.HLAE.KillGapSeconds, "seconds between kills in round_multikill to trigger in-recording gototick jump (0 disables)")
+	flags.Float64Var(&cfg.HLAE.SlowMotionScale, "hlae-slowmo", cfg.HLAE.SlowMotionScale, "playback speed around each kill tick, e.g. 0.3 (0 disables)")
+	flags.Float64Var(&cfg.HLAE.SlowMotionSeconds, "hlae-slowmo-window", cfg.HLAE.SlowMotionSeconds, "seconds of slow motion on each side of a kill tick")
+	flags.IntVar(&cfg.HLAE.ReplaySeconds, "hlae-replay", cfg.HLAE.ReplaySeconds, "re-record each kill from the victim's POV (killer's for deaths), seconds on each side (0 disables)")
+	flags.StringVar(&raw.replayTypes, "hlae-replay-types", "", "comma-separated highlight types that get replays (empty = all)")
+	flags.StringVar(&raw.introTypes, "hlae-intro", "", "comma-separated highlight types that get a mirv_campath intro (all = every type; empty disables)")
+	flags.StringVar(&raw.introStyle, "hlae-intro-style", string(cfg.HLAE.IntroStyle), "campath intro: "+strings.Join(formatNames(hlae.AllIntroStyles()), ","))
+	flags.IntVar(&cfg.HLAE.IntroSeconds, "hlae-intro-seconds", cfg.HLAE.IntroSeconds, "length of the campath intro before a segment")
+	flags.StringVar(&raw.hud, "hlae-hud", string(hlae.HUDKillfeed), "HUD drawn over recordings: "+strings.Join(formatNames(hlae.AllHUDPresets()), ","))
+	flags.Float64Var(&cfg.HLAE.HUD.Lifetime, "hlae-deathmsg-lifetime", cfg.HLAE.HUD.Lifetime, "seconds death notices stay on screen (0 = game default)")
+	flags.StringVar(&cfg.HLAE.HUD.Color, "hlae-deathmsg-color", "", "hex RRGGBB colour of the player's name in their death notices (empty = team colour)")
+	flags.BoolVar(&cfg.HLAE.HUD.HideVictims, "hlae-hide-victims", false, "blank victim names in the death notices")
+	flags.StringVar(&raw.timelines, "timeline", "", "comma-separated editing timelines exported next to each manifest (all = every format): "+strings.Join(formatNames(timeline.AllFormats()), ","))
+	flags.BoolVar(&cfg.FFmpegScripts, "ffmpeg", false, "write an ffmpeg script that cuts, captions and joins the recordings next to each manifest")
+	addFFmpegFlags(flags, &cfg.FFmpeg)
+	flags.StringVar(&raw.captions, "captions", "", "comma-separated subtitle formats written next to each manifest (all = every format): "+strings.Join(formatNames(captions.AllFormats()), ","))
+	addCaptionTemplateFlag(flags, &cfg.Captioner)
+	flags.BoolVar(&cfg.Descriptions, "description", false, "write a Markdown description (map, score, stats, highlights) and YouTube chapters next to each manifest")
+	flags.Float64Var(&cfg.Captioner.Lead, "caption-lead", cfg.Captioner.Lead, "seconds a subtitle shows before the highlight's first kill")
+	flags.Float64Var(&cfg.Captioner.Hold, "caption-hold", cfg.Captioner.Hold, "seconds a subtitle stays after the highlight's last kill")
+	flags.IntVar(&cfg.HLAE.MaxLines, "hlae-max-lines", cfg.HLAE.MaxLines, "split scripts longer than this into part scripts that exec each other (0 keeps one file)")
+}
 
-	if err := flags.Parse(args); err != nil {
-		return Config{}, err
+// apply parses the render flags into cfg.
+func (raw *renderFlags) apply(cfg *Config) error {
+	replayTypes, err := parseTypes(raw.replayTypes)
+	if err != nil {
+		return err
 	}
-	cfg.normalize()
+	cfg.HLAE.ReplayTypes = replayTypes
 
-	selection, err := parseTypes(typesRaw)
+	introTypes, err := parseIntroTypes(raw.introTypes)
 	if err != nil {
-		return Config{}, err
+		return err
 	}
-	cfg.Types = selection
-	cfg.Renders = defaultedRenders(renders)
+	cfg.HLAE.IntroTypes = introTypes
 
-	if err := cfg.Validate(); err != nil {
-		return Config{}, err
+	introStyle, err := parseIntroStyle(raw.introStyle)
+	if err != nil {
+		return err
 	}
+	cfg.HLAE.IntroStyle = introStyle
 
-	return cfg, nil
-}
+	hud, err := parseHUDPreset(raw.hud)
+	if err != nil {
+		return err
+	}
+	cfg.HLAE.HUD.Preset = hud
 
-func highlightTypeNames() []string {
-	types := model.AllHighlightTypes()
-	names := make([]string, 0, len(types))
-	for _, t := range types {
-		names = append(names, string(t))
+	timelines, err := parseFormatList(raw.timelines, timeline.AllFormats(), "timeline")
+	if err != nil {
+		return err
 	}
-	return names
+	cfg.Timelines = timelines
+
+	captionFormats, err := parseFormatList(raw.captions, captions.AllFormats(), "caption")
+	if err != nil {
+		return err
+	}
+	cfg.Captions = captionFormats
+	cfg.FFmpeg.Captions = cfg.Captioner
+
+	profiles, err := loadProfiles(strings.TrimSpace(raw.profiles))
+	if err != nil {
+		return err
+	}
+	targets, err := resolveRenders(raw.renders, cfg.HLAE, profiles)
+	if err != nil {
+		return err
+	}
+	cfg.Renders = defaultedRenders(targets)
+	return nil
 }
 
 // parseTypes turns a comma-separated list of highlight types into a Selection.
@@ -113,7 +279,7 @@ func parseTypes(raw string) (model.Selection, error) {
 		}
 		highlightType := model.HighlightType(name)
 		if !valid[highlightType] {
-			return nil, fmt.Errorf("unknown highlight type %q (valid: %s)", name, strings.Join(highlightTypeNames(), ", "))
+			return nil, fmt.Errorf("unknown highlight type %q (valid: %s)", name, strings.Join(formatNames(model.AllHighlightTypes()), ", "))
 		}
 		selection[highlightType] = true
 	}
@@ -124,10 +290,127 @@ func parseTypes(raw string) (model.Selection, error) {
 	return selection, nil
 }
 
-// appendRender parses a render-target flag value ("[types=]path.cfg") and adds
-// it to renders. Split on the first '=' so Windows drive-letter paths survive.
-func appendRender(renders *[]hlae.Target, mode hlae.Mode, raw string) error {
+// parsePerspective validates the --perspective value. Empty input falls back
+// to kills.
+func parsePerspective(raw string) (model.Perspective, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	if name == "" {
+		return model.PerspectiveKills, nil
+	}
+	for _, p := range model.AllPerspectives() {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown perspective %q (valid: %s)", raw, strings.Join(formatNames(model.AllPerspectives()), ", "))
+}
+
+// parseIntroTypes is parseTypes for opt-in features: empty selects nothing
+// and "all" spells out every type.
+func parseIntroTypes(raw string) (model.Selection, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(trimmed, "all") {
+		selection := make(model.Selection)
+		for _, t := range model.AllHighlightTypes() {
+			selection[t] = true
+		}
+		return selection, nil
+	}
+	return parseTypes(trimmed)
+}
+
+func parseIntroStyle(raw string) (hlae.IntroStyle, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	if name == "" {
+		return hlae.IntroOrbit, nil
+	}
+	for _, s := range hlae.AllIntroStyles() {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown intro style %q (valid: %s)", raw, strings.Join(formatNames(hlae.AllIntroStyles()), ", "))
+}
+
+// parseOutFormat names the output format; empty input leaves it to the
+// output path's extension.
+func parseOutFormat(raw string) (repository.Format, error) {
+	name := repository.Format(strings.ToLower(strings.TrimSpace(raw)))
+	if name == "" || slices.Contains(repository.AllFormats(), name) {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (valid: %s)", raw, strings.Join(formatNames(repository.AllFormats()), ", "))
+}
+
+func parseHUDPreset(raw string) (hlae.HUDPreset, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	if name == "" {
+		return hlae.HUDKillfeed, nil
+	}
+	for _, p := range hlae.AllHUDPresets() {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown hud preset %q (valid: %s)", raw, strings.Join(formatNames(hlae.AllHUDPresets()), ", "))
+}
+
+// parseStreakRules turns "rounds:5,kills:10" into streak rules. Empty input
+// or "none" disables the kill_streak detector.
+func parseStreakRules(raw string) ([]service.StreakRule, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || strings.EqualFold(trimmed, "none") {
+		return nil, nil
+	}
+
+	rules := make([]service.StreakRule, 0)
+	for _, token := range strings.Split(trimmed, ",") {
+		item := strings.TrimSpace(token)
+		if item == "" {
+			continue
+		}
+		kindRaw, minRaw, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("streak %q must be kind:min", item)
+		}
+		kind := service.StreakKind(strings.ToLower(strings.TrimSpace(kindRaw)))
+		if kind != service.StreakRounds && kind != service.StreakKills {
+			return nil, fmt.Errorf("unknown streak kind %q (valid: %s, %s)", kindRaw, service.StreakRounds, service.StreakKills)
+		}
+		minimum, err := strconv.Atoi(strings.TrimSpace(minRaw))
+		if err != nil || minimum < 2 {
+			return nil, fmt.Errorf("streak %q needs a minimum of at least 2", item)
+		}
+		rules = append(rules, service.StreakRule{Kind: kind, Min: minimum})
+	}
+	return rules, nil
+}
+
+func formatStreakRules(rules []service.StreakRule) string {
+	parts := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		parts = append(parts, fmt.Sprintf("%s:%d", rule.Kind, rule.Min))
+	}
+	return strings.Join(parts, ",")
+}
+
+// appendRender parses a render-target flag value
+// ("[types=]path.cfg[;key=value...]") and adds it to renders. Options are
+// split off at the first ';', then types on the first '=' so Windows
+// drive-letter paths survive.
+func appendRender(renders *[]renderSpec, mode hlae.Mode, raw string) error {
 	value := strings.TrimSpace(raw)
+	var options []renderOption
+	if idx := strings.Index(value, ";"); idx >= 0 {
+		var err error
+		if options, err = parseRenderOptions(value[idx+1:]); err != nil {
+			return fmt.Errorf("render target %q: %w", raw, err)
+		}
+		value = value[:idx]
+	}
 	typesRaw := ""
 	pathRaw := value
 	if idx := strings.Index(value, "="); idx >= 0 {
@@ -145,15 +428,76 @@ func appendRender(renders *[]hlae.Target, mode hlae.Mode, raw string) error {
 		return err
 	}
 
-	*renders = append(*renders, hlae.Target{
-		Mode:  mode,
-		Types: types,
-		Path:  path,
-		Name:  nameFromPath(path),
+	*renders = append(*renders, renderSpec{
+		Target: hlae.Target{
+			Mode:  mode,
+			Types: types,
+			Path:  path,
+			Name:  nameFromPath(path),
+		},
+		Options: options,
 	})
 	return nil
 }
 
+// parseRenderOptions parses the ";"-separated key=value options of a render
+// target; see renderOptionSetters for the keys.
+func parseRenderOptions(raw string) ([]renderOption, error) {
+	var options []renderOption
+	for _, item := range strings.Split(raw, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		option, err := parseRenderOption(item)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// parseLayers turns "world,deathmsg:afxFfmpegLosslessBest,depth" into stream
+// layers named after their kind.
+func parseLayers(raw string) ([]hlae.StreamLayer, error) {
+	var layers []hlae.StreamLayer
+	seen := make(map[hlae.LayerKind]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kindRaw, preset, _ := strings.Cut(part, ":")
+		kind, err := parseLayerKind(kindRaw)
+		if err != nil {
+			return nil, err
+		}
+		preset = strings.TrimSpace(preset)
+		if preset != "" && !presetPattern.MatchString(preset) {
+			return nil, fmt.Errorf("layer %q: preset %q may only hold letters, digits and _", kind, preset)
+		}
+		if seen[kind] {
+			return nil, fmt.Errorf("layer %q listed twice", kind)
+		}
+		seen[kind] = true
+		layers = append(layers, hlae.StreamLayer{Name: string(kind), Kind: kind, Preset: preset})
+	}
+	return layers, nil
+}
+
+func parseLayerKind(raw string) (hlae.LayerKind, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	names := make([]string, 0, len(hlae.AllLayerKinds()))
+	for _, kind := range hlae.AllLayerKinds() {
+		if string(kind) == name {
+			return kind, nil
+		}
+		names = append(names, string(kind))
+	}
+	return "", fmt.Errorf("unknown layer %q (valid: %s)", raw, strings.Join(names, ", "))
+}
+
 func nameFromPath(path string) string {
 	base := filepath.Base(path)
 	return strings.TrimSuffix(base, filepath.Ext(base))
@@ -169,34 +513,145 @@ func defaultedRenders(renders []hlae.Target) []hlae.Target {
 }
 
 func (c *Config) normalize() {
-	c.DemoPath = strings.TrimSpace(c.DemoPath)
+	for i, path := range c.DemoPaths {
+		c.DemoPaths[i] = strings.TrimSpace(path)
+	}
 	c.SteamID = strings.TrimSpace(c.SteamID)
 	c.OutputPath = strings.TrimSpace(c.OutputPath)
+	c.LockPath = strings.TrimSpace(c.LockPath)
 
 	c.HLAE.OutputPath = strings.TrimSpace(c.HLAE.OutputPath)
 	c.HLAE.FFmpegPreset = strings.TrimSpace(c.HLAE.FFmpegPreset)
+	c.HLAE.HUD.Color = strings.TrimPrefix(strings.TrimSpace(c.HLAE.HUD.Color), "#")
+	c.FFmpeg.FontFile = strings.TrimSpace(c.FFmpeg.FontFile)
 }
 
 func (c Config) Validate() error {
-	if err := service.ValidateSteamID(c.SteamID); err != nil {
+	if c.Team {
+		if c.Perspective == model.PerspectiveDeaths {
+			return fmt.Errorf("--team ranks kill highlights and cannot be combined with --perspective %s", model.PerspectiveDeaths)
+		}
+	} else if err := service.ValidateSteamID(c.SteamID); err != nil {
+		return err
+	}
+	if len(c.DemoPaths) == 0 {
+		return demo.ValidatePath("")
+	}
+	for _, path := range c.DemoPaths {
+		if err := demo.ValidatePath(path); err != nil {
+			return err
+		}
+	}
+
+	for _, check := range []struct {
+		flag  string
+		value int
+	}{
+		{flag: "low-hp", value: c.LowHP},
+		{flag: "top", value: c.Top},
+	} {
+		if check.value < 0 {
+			return fmt.Errorf("%s must be >= 0", check.flag)
+		}
+	}
+	return c.validateRender()
+}
+
+// validateRender checks the settings shared by the main run and the render
+// command.
+func (c Config) validateRender() error {
+	if err := validateHLAEOptions(c.HLAE); err != nil {
 		return err
 	}
-	if err := demo.ValidatePath(c.DemoPath); err != nil {
+	if err := validateFFmpegBuilder(c.FFmpeg); err != nil {
 		return err
 	}
+	if len(c.Captions) > 0 {
+		if err := validateCaptioner(c.Captioner); err != nil {
+			return err
+		}
+	}
+	if c.LockWait < 0 {
+		return fmt.Errorf("lock-wait must be >= 0")
+	}
+	for _, target := range c.Renders {
+		if target.Options == nil {
+			continue
+		}
+		if err := validateHLAEOptions(*target.Options); err != nil {
+			return fmt.Errorf("render target %q: %w", target.Path, err)
+		}
+	}
+
+	return nil
+}
 
+// validateHLAEOptions checks render options, whether shared or a target's
+// own, naming the --hlae-* flag that sets each.
+func validateHLAEOptions(o hlae.Options) error {
 	for _, check := range []struct {
 		flag  string
 		value int
 	}{
-		{flag: "hlae-preroll", value: c.HLAE.PreRollSeconds},
-		{flag: "hlae-postroll", value: c.HLAE.PostRollSeconds},
-		{flag: "hlae-kill-gap", value: c.HLAE.KillGapSeconds},
+		{flag: "hlae-preroll", value: o.PreRollSeconds},
+		{flag: "hlae-postroll", value: o.PostRollSeconds},
+		{flag: "hlae-kill-gap", value: o.KillGapSeconds},
+		{flag: "hlae-replay", value: o.ReplaySeconds},
+		{flag: "hlae-intro-seconds", value: o.IntroSeconds},
+		{flag: "hlae-max-lines", value: o.MaxLines},
 	} {
 		if check.value < 0 {
 			return fmt.Errorf("%s must be >= 0", check.flag)
 		}
 	}
+	if o.SlowMotionScale < 0 || o.SlowMotionScale >= 1 {
+		return fmt.Errorf("hlae-slowmo must be 0 (disabled) or between 0 and 1, got %v", o.SlowMotionScale)
+	}
+	if o.SlowMotionSeconds < 0 {
+		return fmt.Errorf("hlae-slowmo-window must be >= 0")
+	}
+	if o.HUD.Lifetime < 0 {
+		return fmt.Errorf("hlae-deathmsg-lifetime must be >= 0")
+	}
+	if o.HUD.Color != "" && !hexColorPattern.MatchString(o.HUD.Color) {
+		return fmt.Errorf("hlae-deathmsg-color must be a hex RRGGBB colour, got %q", o.HUD.Color)
+	}
 
 	return nil
 }
+
+// formatNames lists the names of all, for flag help and error messages.
+func formatNames[F ~string](all []F) []string {
+	names := make([]string, 0, len(all))
+	for _, f := range all {
+		names = append(names, string(f))
+	}
+	return names
+}
+
+// parseFormatList turns "edl,otio" into the formats of all it names, in the
+// order given. Empty input selects none; "all" selects every format. kind
+// names the flag's formats in errors, e.g. "timeline".
+func parseFormatList[F ~string](raw string, all []F, kind string) ([]F, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(trimmed, "all") {
+		return slices.Clone(all), nil
+	}
+	var formats []F
+	seen := make(map[F]bool)
+	for _, token := range strings.Split(trimmed, ",") {
+		name := F(strings.ToLower(strings.TrimSpace(token)))
+		if name == "" || seen[name] {
+			continue
+		}
+		if !slices.Contains(all, name) {
+			return nil, fmt.Errorf("unknown %s format %q (valid: %s)", kind, strings.TrimSpace(token), strings.Join(formatNames(all), ", "))
+		}
+		seen[name] = true
+		formats = append(formats, name)
+	}
+	return formats, nil
+}
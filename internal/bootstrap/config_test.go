@@ -1,14 +1,23 @@
 package bootstrap
 
 import (
+	"bytes"
 	"errors"
+	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/eSheikh/cs2-demo-highlighter/internal/captions"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/demo"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/publish"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/repository"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/service"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/timeline"
 )
 
 func TestConfigValidateDemoPathAndSteamID(t *testing.T) {
@@ -37,27 +46,27 @@ func TestConfigValidateDemoPathAndSteamID(t *testing.T) {
 		{
 			name: "invalid extension",
 			config: Config{
-				DemoPath: filepath.Join(tempDir, "match.txt"),
-				SteamID:  "76561197960265728",
-				HLAE:     hlae.Options{},
+				DemoPaths: []string{filepath.Join(tempDir, "match.txt")},
+				SteamID:   "76561197960265728",
+				HLAE:      hlae.Options{},
 			},
 			expectErr: demo.ErrInvalidFileExtension,
 		},
 		{
 			name: "invalid steamid",
 			config: Config{
-				DemoPath: validDemo,
-				SteamID:  "7656119",
-				HLAE:     hlae.Options{},
+				DemoPaths: []string{validDemo},
+				SteamID:   "7656119",
+				HLAE:      hlae.Options{},
 			},
 			wantErr: true,
 		},
 		{
 			name: "valid config",
 			config: Config{
-				DemoPath: validDemo,
-				SteamID:  "76561197960265728",
-				HLAE:     hlae.Options{},
+				DemoPaths: []string{validDemo},
+				SteamID:   "76561197960265728",
+				HLAE:      hlae.Options{},
 			},
 		},
 	}
@@ -113,8 +122,8 @@ func TestParseConfigTrimsCLIInputs(t *testing.T) {
 		t.Fatalf("parse config: %v", err)
 	}
 
-	if cfg.DemoPath != validDemo {
-		t.Fatalf("expected trimmed demo path %q, got %q", validDemo, cfg.DemoPath)
+	if len(cfg.DemoPaths) != 1 || cfg.DemoPaths[0] != validDemo {
+		t.Fatalf("expected trimmed demo path %q, got %q", validDemo, cfg.DemoPaths)
 	}
 	if cfg.SteamID != "76561197960265728" {
 		t.Fatalf("expected trimmed steamid, got %q", cfg.SteamID)
@@ -219,3 +228,446 @@ func TestParseConfigDefaultOutputPath(t *testing.T) {
 		t.Fatalf("expected default output path %q, got %q", expected, cfg.HLAE.OutputPath)
 	}
 }
+
+func TestParseConfigPerspective(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	validDemo := filepath.Join(tempDir, "valid.dem")
+	if err := os.WriteFile(validDemo, []byte("demo-content"), 0o644); err != nil {
+		t.Fatalf("write valid demo: %v", err)
+	}
+
+	cfg, err := ParseConfig([]string{"--demo", validDemo, "--steamid", "76561197960265728"})
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if cfg.Perspective != model.PerspectiveKills {
+		t.Fatalf("expected default kills perspective, got %q", cfg.Perspective)
+	}
+
+	cfg, err = ParseConfig([]string{"--demo", validDemo, "--steamid", "76561197960265728", "--perspective", " Deaths "})
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if cfg.Perspective != model.PerspectiveDeaths {
+		t.Fatalf("expected deaths perspective, got %q", cfg.Perspective)
+	}
+
+	if _, err := ParseConfig([]string{"--demo", validDemo, "--steamid", "76561197960265728", "--perspective", "assists"}); err == nil {
+		t.Fatalf("expected error for unknown perspective")
+	}
+}
+
+func TestParseStreakRules(t *testing.T) {
+	t.Parallel()
+
+	rules, err := parseStreakRules(" rounds:6, kills:12 ")
+	if err != nil {
+		t.Fatalf("parse streaks: %v", err)
+	}
+	if len(rules) != 2 || rules[0] != (service.StreakRule{Kind: service.StreakRounds, Min: 6}) || rules[1] != (service.StreakRule{Kind: service.StreakKills, Min: 12}) {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	if rules, err := parseStreakRules("none"); err != nil || rules != nil {
+		t.Fatalf("expected none to disable streaks, got %+v, %v", rules, err)
+	}
+	for _, raw := range []string{"rounds", "deaths:3", "kills:1", "kills:x"} {
+		if _, err := parseStreakRules(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestParseConfigTeamSkipsSteamID(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	validDemo := filepath.Join(tempDir, "valid.dem")
+	if err := os.WriteFile(validDemo, []byte("demo-content"), 0o644); err != nil {
+		t.Fatalf("write valid demo: %v", err)
+	}
+
+	cfg, err := ParseConfig([]string{"--demo", validDemo, "--team", "--top", "10"})
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if !cfg.Team || cfg.Top != 10 {
+		t.Fatalf("unexpected team config: team=%v top=%d", cfg.Team, cfg.Top)
+	}
+
+	if _, err := ParseConfig([]string{"--demo", validDemo, "--team", "--perspective", "deaths"}); err == nil {
+		t.Fatalf("expected error for team reel of deaths")
+	}
+	if _, err := ParseConfig([]string{"--demo", validDemo, "--team", "--top", "-1"}); err == nil {
+		t.Fatalf("expected error for negative top")
+	}
+}
+
+func TestParseConfigSlowMotion(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	validDemo := filepath.Join(tempDir, "valid.dem")
+	if err := os.WriteFile(validDemo, []byte("demo-content"), 0o644); err != nil {
+		t.Fatalf("write valid demo: %v", err)
+	}
+
+	cfg, err := ParseConfig([]string{"--demo", validDemo, "--steamid", "76561197960265728", "--hlae-slowmo", "0.3", "--hlae-slowmo-window", "1.5"})
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if cfg.HLAE.SlowMotionScale != 0.3 || cfg.HLAE.SlowMotionSeconds != 1.5 {
+		t.Fatalf("unexpected slow motion: %+v", cfg.HLAE)
+	}
+
+	for _, raw := range []string{"1", "-0.5", "2"} {
+		if _, err := ParseConfig([]string{"--demo", validDemo, "--steamid", "76561197960265728", "--hlae-slowmo", raw}); err == nil {
+			t.Fatalf("expected error for hlae-slowmo %s", raw)
+		}
+	}
+}
+
+func TestParseIntroOptions(t *testing.T) {
+	t.Parallel()
+
+	if types, err := parseIntroTypes(""); err != nil || types != nil {
+		t.Fatalf("expected no intros by default, got %v, %v", types, err)
+	}
+	types, err := parseIntroTypes("all")
+	if err != nil || len(types) != len(model.AllHighlightTypes()) {
+		t.Fatalf("expected every type for all, got %v, %v", types, err)
+	}
+	if types, err := parseIntroTypes("clutch_win"); err != nil || !types[model.HighlightClutchWin] || len(types) != 1 {
+		t.Fatalf("unexpected intro types: %v, %v", types, err)
+	}
+
+	if style, err := parseIntroStyle(" Spawn "); err != nil || style != hlae.IntroSpawn {
+		t.Fatalf("unexpected intro style: %q, %v", style, err)
+	}
+	if _, err := parseIntroStyle("dolly"); err == nil {
+		t.Fatalf("expected error for unknown intro style")
+	}
+}
+
+func TestParseConfigTimelinesExportManifests(t *testing.T) {
+	t.Parallel()
+
+	if formats, err := parseFormatList(" EDL, otio,edl ", timeline.AllFormats(), "timeline"); err != nil || !reflect.DeepEqual(formats, []timeline.Format{timeline.FormatEDL, timeline.FormatOTIO}) {
+		t.Fatalf("unexpected timeline formats: %v, %v", formats, err)
+	}
+	if formats, err := parseFormatList("all", timeline.AllFormats(), "timeline"); err != nil || len(formats) != len(timeline.AllFormats()) {
+		t.Fatalf("expected every format for all, got %v, %v", formats, err)
+	}
+	if _, err := parseFormatList("aaf", timeline.AllFormats(), "timeline"); err == nil {
+		t.Fatalf("expected error for unknown timeline format")
+	}
+
+	result := model.HighlightResult{Demo: "mirage.dem", TickRate: 64, Highlights: []model.Highlight{
+		{Type: model.HighlightHeadshot, Round: 3, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000},
+	}}
+	target := hlae.Target{Mode: hlae.ModeMontage, Path: "out/reel.cfg", Name: "reel"}
+	files, err := hlae.BuildTarget(result, hlae.Options{PreRollSeconds: 1, PostRollSeconds: 1}, target)
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	timelines, err := timelineFiles(target, files, []timeline.Format{timeline.FormatEDL, timeline.FormatFCPXML})
+	if err != nil {
+		t.Fatalf("timelines: %v", err)
+	}
+	if len(timelines) != 2 || timelines[0].Path != "out/reel.edl" || timelines[1].Path != "out/reel.fcpxml" {
+		t.Fatalf("expected timelines next to the manifest, got %+v", timelines)
+	}
+	if !strings.Contains(timelines[0].Content, "* LOC: 01:00:01:00 RED     headshot_kill r3\n") {
+		t.Fatalf("expected the kill one second into the take:\n%s", timelines[0].Content)
+	}
+	if timelines, err := timelineFiles(target, files[:1], timeline.AllFormats()); err != nil || timelines != nil {
+		t.Fatalf("expected no timelines without a manifest, got %+v, %v", timelines, err)
+	}
+}
+
+func TestParseConfigWritesFFmpegScripts(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	validDemo := filepath.Join(tempDir, "valid.dem")
+	if err := os.WriteFile(validDemo, []byte("demo-content"), 0o644); err != nil {
+		t.Fatalf("write valid demo: %v", err)
+	}
+	base := []string{"--demo", validDemo, "--steamid", "76561197960265728", "--montage", "out/reel.cfg"}
+
+	cfg, err := ParseConfig(append(base, "--ffmpeg", "--ffmpeg-lead", "1.5", "--ffmpeg-fade", "0", "--ffmpeg-font", " C:/Windows/Fonts/arial.ttf "))
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if !cfg.FFmpegScripts || cfg.FFmpeg.Lead != 1.5 || cfg.FFmpeg.Fade != 0 || cfg.FFmpeg.CaptionSeconds != 3 || cfg.FFmpeg.FontFile != "C:/Windows/Fonts/arial.ttf" {
+		t.Fatalf("unexpected ffmpeg options: %+v", cfg.FFmpeg)
+	}
+	if _, err := ParseConfig(append(base, "--ffmpeg-captions", "-1")); err == nil {
+		t.Fatalf("expected error for negative caption seconds")
+	}
+
+	result := model.HighlightResult{Demo: "mirage.dem", TickRate: 64, Highlights: []model.Highlight{
+		{Type: model.HighlightHeadshot, Round: 3, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000},
+	}}
+	target := cfg.Renders[0]
+	files, err := hlae.BuildTarget(result, cfg.HLAE, target)
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	jobFiles, err := ffmpegFiles(target, files, &cfg.FFmpeg)
+	if err != nil {
+		t.Fatalf("ffmpeg files: %v", err)
+	}
+	if len(jobFiles) != 4 || filepath.ToSlash(jobFiles[0].Path) != "out/reel.ffmpeg.sh" || !strings.Contains(jobFiles[0].Content, "-ss 1.5 -i ") {
+		t.Fatalf("expected the script, list, chapters and caption, got %+v", jobFiles)
+	}
+}
+
+func TestParseConfigWritesCaptions(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	validDemo := filepath.Join(tempDir, "valid.dem")
+	if err := os.WriteFile(validDemo, []byte("demo-content"), 0o644); err != nil {
+		t.Fatalf("write valid demo: %v", err)
+	}
+	base := []string{"--demo", validDemo, "--steamid", "76561197960265728", "--montage", "out/reel.cfg"}
+
+	cfg, err := ParseConfig(append(base, "--captions", "ASS, srt,ass", "--caption-template", "R{round}: {label}", "--caption-template", "headshot_kill=one tap[ on {victims}]", "--ffmpeg"))
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Captions, []captions.Format{captions.FormatASS, captions.FormatSRT}) {
+		t.Fatalf("expected deduplicated formats in order, got %v", cfg.Captions)
+	}
+	if cfg.Captioner.Template.String() != "R{round}: {label}" || cfg.Captioner.Templates[model.HighlightHeadshot].String() != "one tap[ on {victims}]" {
+		t.Fatalf("unexpected templates: %+v", cfg.Captioner)
+	}
+	if cfg.FFmpeg.Captions.Template.String() != "R{round}: {label}" {
+		t.Fatalf("expected ffmpeg captions to share the templates, got %+v", cfg.FFmpeg.Captions)
+	}
+	for _, args := range [][]string{{"--captions", "vtt"}, {"--caption-template", "{map}"}, {"--captions", "srt", "--caption-hold", "0"}} {
+		if _, err := ParseConfig(append(base, args...)); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+
+	result := model.HighlightResult{Demo: "mirage.dem", TickRate: 64, Highlights: []model.Highlight{
+		{Type: model.HighlightHeadshot, Round: 3, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000, VictimNames: []string{"enemy"}},
+	}}
+	target := cfg.Renders[0]
+	files, err := hlae.BuildTarget(result, cfg.HLAE, target)
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	subtitles, err := captionFiles(target, files, cfg.Captions, &cfg.Captioner)
+	if err != nil {
+		t.Fatalf("caption files: %v", err)
+	}
+	if len(subtitles) != 2 || filepath.ToSlash(subtitles[1].Path) != "out/reel.srt" || !strings.Contains(subtitles[1].Content, "\none tap on enemy\n") {
+		t.Fatalf("expected ass and srt captions, got %+v", subtitles)
+	}
+}
+
+func TestParseConfigWritesDescriptions(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	validDemo := filepath.Join(tempDir, "valid.dem")
+	if err := os.WriteFile(validDemo, []byte("demo-content"), 0o644); err != nil {
+		t.Fatalf("write valid demo: %v", err)
+	}
+	cfg, err := ParseConfig([]string{"--demo", validDemo, "--steamid", "76561197960265728", "--montage", "out/reel.cfg", "--description", "--caption-template", "R{round} {label}"})
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if !cfg.Descriptions {
+		t.Fatalf("expected descriptions enabled")
+	}
+
+	result := model.HighlightResult{Demo: "mirage.dem", Map: "de_mirage", TickRate: 64, Highlights: []model.Highlight{
+		{Type: model.HighlightHeadshot, Round: 3, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000},
+	}}
+	target := cfg.Renders[0]
+	files, err := hlae.BuildTarget(result, cfg.HLAE, target)
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	var out bytes.Buffer
+	description, err := descriptionFiles(target, files, []model.HighlightResult{result}, &publish.Writer{Captions: cfg.Captioner}, log.New(&out, "", 0))
+	if err != nil {
+		t.Fatalf("description files: %v", err)
+	}
+	if len(description) != 1 || filepath.ToSlash(description[0].Path) != "out/reel.description.md" || strings.Contains(description[0].Content, "## Chapters") {
+		t.Fatalf("expected a description without chapters, got %+v", description)
+	}
+	if !strings.Contains(out.String(), "skipping chapters: YouTube needs at least 3 chapters of 10s or more, the montage has 1") {
+		t.Fatalf("expected the skipped chapters logged, got %q", out.String())
+	}
+
+	result.Highlights = append(result.Highlights,
+		model.Highlight{Type: model.HighlightWallbang, Round: 9, PlayerSlot: 7, SegmentFrom: 5000, SegmentTo: 5000, TickStart: 5000},
+		model.Highlight{Type: model.HighlightHeadshot, Round: 14, PlayerSlot: 7, SegmentFrom: 9000, SegmentTo: 9000, TickStart: 9000},
+	)
+	options := cfg.HLAE
+	options.PostRollSeconds = 10
+	if files, err = hlae.BuildTarget(result, options, target); err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	if description, err = descriptionFiles(target, files, []model.HighlightResult{result}, &publish.Writer{Captions: cfg.Captioner}, nil); err != nil {
+		t.Fatalf("description files: %v", err)
+	}
+	if len(description) != 2 || filepath.ToSlash(description[1].Path) != "out/reel.chapters.txt" || !strings.HasPrefix(description[1].Content, "00:00 R3 headshot\n") {
+		t.Fatalf("expected a description and chapters, got %+v", description)
+	}
+}
+
+func TestParseConfigOutFormat(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	validDemo := filepath.Join(tempDir, "valid.dem")
+	if err := os.WriteFile(validDemo, []byte("demo-content"), 0o644); err != nil {
+		t.Fatalf("write valid demo: %v", err)
+	}
+	base := []string{"--demo", validDemo, "--steamid", "76561197960265728", "--out", "highlights.csv"}
+
+	cfg, err := ParseConfig(base)
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if cfg.OutFormat != "" {
+		t.Fatalf("expected the format left to the extension, got %q", cfg.OutFormat)
+	}
+	cfg, err = ParseConfig(append(base, "--out-format", " NDJSON "))
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if cfg.OutFormat != repository.FormatNDJSON {
+		t.Fatalf("expected ndjson, got %q", cfg.OutFormat)
+	}
+	if _, err := ParseConfig(append(base, "--out-format", "xml")); err == nil {
+		t.Fatalf("expected error for unknown output format")
+	}
+}
+
+func TestAppendRenderParsesLayers(t *testing.T) {
+	var renders []renderSpec
+	if err := appendRender(&renders, hlae.ModeClips, "wallbang=C:/cfg/clips.cfg;layers=world,deathmsg:afxFfmpegLosslessBest,depth"); err != nil {
+		t.Fatalf("append render: %v", err)
+	}
+	targets, err := resolveRenders(renders, hlae.Options{}, nil)
+	if err != nil {
+		t.Fatalf("resolve renders: %v", err)
+	}
+	target := targets[0]
+	if target.Path != "C:/cfg/clips.cfg" || !target.Types[model.HighlightWallbang] || target.Options != nil {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+	want := []hlae.StreamLayer{
+		{Name: "world", Kind: hlae.LayerWorld},
+		{Name: "deathmsg", Kind: hlae.LayerDeathmsg, Preset: "afxFfmpegLosslessBest"},
+		{Name: "depth", Kind: hlae.LayerDepth},
+	}
+	if !reflect.DeepEqual(target.Layers, want) {
+		t.Fatalf("expected layers %+v, got %+v", want, target.Layers)
+	}
+
+	if _, err := parseLayers("world:x disconnect"); err == nil {
+		t.Fatalf("expected a preset with spaces to be rejected")
+	}
+	for _, raw := range []string{"clips.cfg;layers=matte", "clips.cfg;layers=world,world", "clips.cfg;speed=30", "clips.cfg;layers=world:afx\"Best"} {
+		renders = nil
+		if err := appendRender(&renders, hlae.ModeClips, raw); err == nil {
+			if _, err = resolveRenders(renders, hlae.Options{}, nil); err == nil {
+				t.Fatalf("expected error for %q", raw)
+			}
+		}
+	}
+}
+
+func TestParseConfigAppliesPerTargetOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	validDemo := filepath.Join(tempDir, "valid.dem")
+	if err := os.WriteFile(validDemo, []byte("demo-content"), 0o644); err != nil {
+		t.Fatalf("write valid demo: %v", err)
+	}
+	profiles := filepath.Join(tempDir, "profiles.json")
+	content := `{"profiles": {"slowmo": {"fps": 240, "slowmo": 0.3, "preset": "afxFfmpegLosslessBest", "layers": "world,depth", "hide-victims": true}}}`
+	if err := os.WriteFile(profiles, []byte(content), 0o644); err != nil {
+		t.Fatalf("write profiles: %v", err)
+	}
+
+	cfg, err := ParseConfig([]string{
+		"--demo", validDemo,
+		"--steamid", "76561197960265728",
+		"--profiles", profiles,
+		"--clips", "clips.cfg;recorder=console",
+		"--montage", "reel.cfg;profile=slowmo;fps=120;preroll=5;hud=highlight;deathmsg-color=#FF4040",
+		"--hlae-fps", "60",
+		"--hlae-preroll", "2",
+	})
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if cfg.Renders[0].Options != nil || cfg.Renders[0].Recorder != "console" {
+		t.Fatalf("expected the clips target on the console recorder with the shared options, got %+v", cfg.Renders[0])
+	}
+	montage := cfg.Renders[1]
+	if montage.Options == nil {
+		t.Fatalf("expected the montage target to override options")
+	}
+	got := *montage.Options
+	if got.FrameRate != 120 || got.SlowMotionScale != 0.3 || got.FFmpegPreset != "afxFfmpegLosslessBest" || got.PreRollSeconds != 5 || got.PostRollSeconds != 2 {
+		t.Fatalf("expected profile and target options over the shared ones, got %+v", got)
+	}
+	if got.HUD != (hlae.HUD{Preset: hlae.HUDHighlight, Color: "FF4040", HideVictims: true}) {
+		t.Fatalf("expected the target's HUD, got %+v", got.HUD)
+	}
+	if len(montage.Layers) != 2 || montage.Layers[1].Kind != hlae.LayerDepth {
+		t.Fatalf("expected the profile's layers, got %+v", montage.Layers)
+	}
+
+	for _, args := range [][]string{
+		{"--montage", "reel.cfg;profile=missing"},
+		{"--montage", "reel.cfg;slowmo=2"},
+		{"--montage", "reel.cfg;fps=fast"},
+		{"--montage", "reel.cfg;hud=minimal"},
+		{"--montage", "reel.cfg;recorder=vlc"},
+		{"--montage", "reel.cfg;deathmsg-color=red"},
+	} {
+		args = append([]string{"--demo", validDemo, "--steamid", "76561197960265728", "--profiles", profiles}, args...)
+		if _, err := ParseConfig(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseConfigChainsRepeatedDemos(t *testing.T) {
+	tempDir := t.TempDir()
+	var args []string
+	for _, name := range []string{"mirage.dem", "inferno.dem"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte("demo-content"), 0o644); err != nil {
+			t.Fatalf("write demo: %v", err)
+		}
+		args = append(args, "--demo", path)
+	}
+
+	cfg, err := ParseConfig(append(args, "--steamid", "76561197960265728"))
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	if len(cfg.DemoPaths) != 2 || filepath.Base(cfg.DemoPaths[1]) != "inferno.dem" {
+		t.Fatalf("expected both demos in order, got %v", cfg.DemoPaths)
+	}
+
+	if got := resultOutputPath("out/highlights.json", 1, cfg.DemoPaths[1], 2); got != "out/highlights.02_inferno.json" {
+		t.Fatalf("expected per-demo output path, got %q", got)
+	}
+	if got := resultOutputPath("highlights.json", 0, cfg.DemoPaths[0], 1); got != "highlights.json" {
+		t.Fatalf("expected --out as is for a single demo, got %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/ffmpeg"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+)
+
+// runFFmpeg implements "highlighter ffmpeg [--dry-run] target.manifest.json...":
+// it cuts, captions and joins the recordings of each manifest with the
+// local ffmpeg, or with --dry-run only writes the script and its files.
+func runFFmpeg(ctx context.Context, args []string, logger *log.Logger) error {
+	builder := ffmpeg.NewBuilder()
+	runner := ffmpeg.NewRunner()
+	var dryRun bool
+	flags := flag.NewFlagSet("highlighter ffmpeg", flag.ContinueOnError)
+	addFFmpegFlags(flags, builder)
+	addCaptionTemplateFlag(flags, &builder.Captions)
+	flags.StringVar(&runner.Binary, "bin", runner.Binary, "ffmpeg executable")
+	flags.BoolVar(&dryRun, "dry-run", false, "write the script, concat list and chapters without running ffmpeg")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("ffmpeg needs at least one .manifest.json path")
+	}
+	builder.FontFile = strings.TrimSpace(builder.FontFile)
+	if err := validateFFmpegBuilder(*builder); err != nil {
+		return err
+	}
+	runner.Logf = func(format string, args ...any) { logf(logger, format, args...) }
+
+	for _, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var manifest hlae.Manifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Errorf("parse manifest %s: %w", path, err)
+		}
+		job, err := builder.Build(manifest, strings.TrimSuffix(path, ".manifest.json"))
+		if err != nil {
+			return err
+		}
+		logf(logger, "%s: %d parts into %s", path, len(job.Parts), job.Output)
+		if dryRun {
+			for _, file := range job.Files() {
+				if err := writeHLAEScriptFile(file.Path, file.Content, logger); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := runner.Run(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFFmpegFlags registers the cut options shared by the main run and the
+// ffmpeg command.
+func addFFmpegFlags(flags *flag.FlagSet, builder *ffmpeg.Builder) {
+	flags.Float64Var(&builder.Lead, "ffmpeg-lead", builder.Lead, "seconds kept before the first kill of each part (-1 keeps the whole pre-roll)")
+	flags.Float64Var(&builder.Fade, "ffmpeg-fade", builder.Fade, "seconds of fade in and out on each part (0 disables)")
+	flags.Float64Var(&builder.CaptionSeconds, "ffmpeg-captions", builder.CaptionSeconds, "seconds each part's caption is burnt in (0 disables)")
+	flags.StringVar(&builder.FontFile, "ffmpeg-font", builder.FontFile, "font file for captions (empty = fontconfig default)")
+}
+
+func validateFFmpegBuilder(builder ffmpeg.Builder) error {
+	if builder.Fade < 0 {
+		return errors.New("ffmpeg-fade must be >= 0")
+	}
+	if builder.CaptionSeconds < 0 {
+		return errors.New("ffmpeg-captions must be >= 0")
+	}
+	return nil
+}
+
+// ffmpegFiles writes the ffmpeg job of the target's manifest next to it.
+// Recorders that write no manifest get none.
+func ffmpegFiles(target hlae.Target, files []hlae.File, builder *ffmpeg.Builder) ([]hlae.File, error) {
+	manifest, ok, err := targetManifest(target, files)
+	if err != nil || !ok {
+		return nil, err
+	}
+	path := hlae.ManifestPath(target.Path)
+	job, err := builder.Build(manifest, strings.TrimSuffix(path, ".manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	return job.Files(), nil
+}
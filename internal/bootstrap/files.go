@@ -3,11 +3,13 @@ package bootstrap
 import (
 	"os"
 	"path/filepath"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/atomicfile"
 )
 
 func writeTextFile(path string, content string) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
-	return os.WriteFile(path, []byte(content), 0o644)
+	return atomicfile.WriteFile(path, []byte(content), 0o644)
 }
@@ -0,0 +1,81 @@
+package bootstrap
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+)
+
+// runLint implements "highlighter lint [--last-tick N] script.cfg...": it
+// simulates each script, following the part scripts it execs, and logs what
+// it finds. It fails when any script has errors.
+func runLint(args []string, logger *log.Logger) error {
+	var options hlae.LintOptions
+	flags := flag.NewFlagSet("highlighter lint", flag.ContinueOnError)
+	flags.IntVar(&options.LastTick, "last-tick", 0, "last tick of the demo, to catch segments past its end (0 skips the check)")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("lint needs at least one .cfg path")
+	}
+	if options.LastTick < 0 {
+		return errors.New("last-tick must be >= 0")
+	}
+
+	failed := 0
+	for _, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		options.Exec = execResolver(path)
+		report := hlae.Lint(string(data), options)
+		for _, issue := range report.Issues {
+			logf(logger, "%s:%s", path, issue)
+		}
+		logf(logger, "%s: %d takes, %d issues, %d errors", path, len(report.Takes), len(report.Issues), report.Errors())
+		if report.Errors() > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("lint found errors in %d of %d scripts", failed, flags.NArg())
+	}
+	return nil
+}
+
+// execResolver finds the scripts a script at path execs. Exec names are
+// relative to csgo/cfg, which is the script's own folder or the one above it
+// (chained and split scripts sit in subfolders), so each is looked up in
+// those two folders only.
+func execResolver(path string) func(name string) (string, bool) {
+	return func(name string) (string, bool) {
+		file := filepath.FromSlash(name)
+		if filepath.Ext(file) == "" {
+			file += ".cfg"
+		}
+		dir, _ := filepath.Abs(filepath.Dir(path))
+		for _, root := range []string{dir, filepath.Dir(dir)} {
+			if data, err := os.ReadFile(filepath.Join(root, file)); err == nil {
+				return string(data), true
+			}
+		}
+		return "", false
+	}
+}
+
+func logf(logger *log.Logger, format string, args ...any) {
+	if logger == nil {
+		return
+	}
+	logger.Printf(format, args...)
+}
@@ -0,0 +1,92 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLintFailsOnScriptErrors(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.cfg")
+	bad := filepath.Join(dir, "bad.cfg")
+	scripts := map[string]string{
+		good: "mirv_cmd clear;\nmirv_cmd addAtTick 100 \"mirv_streams record start\";\nmirv_cmd addAtTick 200 \"mirv_streams record end\";\n",
+		bad:  "mirv_cmd clear;\nmirv_cmd addAtTick 100 \"mirv_streams record start\";\n",
+	}
+	for path, content := range scripts {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write script: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	logger := log.New(&out, "", 0)
+	if err := Run(context.Background(), []string{"lint", good}, logger); err != nil {
+		t.Fatalf("expected clean lint, got %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "good.cfg: 1 takes, 0 issues, 0 errors") {
+		t.Fatalf("expected summary line, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := Run(context.Background(), []string{"lint", "--last-tick", "150", good, bad}, logger); err == nil {
+		t.Fatalf("expected lint to fail")
+	}
+	for _, want := range []string{"good.cfg:3: error: recording runs past the demo's last tick 150", "bad.cfg:2: error: record start without a matching record end"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunLintFollowsPartScripts(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		filepath.Join(dir, "reel.cfg"):           "mirv_cmd clear;\nexec reel/part01;\n",
+		filepath.Join(dir, "reel", "part01.cfg"): "mirv_cmd addAtTick 100 \"mirv_streams record start\";\nmirv_cmd addAtTick 200 \"exec reel/part02; mirv_streams record end\";\n",
+		filepath.Join(dir, "reel", "part02.cfg"): "mirv_cmd addAtTick 300 \"mirv_streams record start\";\n",
+	}
+	for path, content := range scripts {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write script: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	err := Run(context.Background(), []string{"lint", filepath.Join(dir, "reel.cfg")}, log.New(&out, "", 0))
+	if err == nil {
+		t.Fatalf("expected the open recording in part02 to fail lint")
+	}
+	if !strings.Contains(out.String(), "reel/part02:1: error: record start without a matching record end") {
+		t.Fatalf("expected the issue to name the part script, got:\n%s", out.String())
+	}
+}
+
+func TestRunLintLooksUpExecsNoHigherThanTheParentFolder(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		filepath.Join(dir, "cfg", "clips", "reel.cfg"): "mirv_cmd clear;\nexec reel/part01;\n",
+		filepath.Join(dir, "reel", "part01.cfg"):       "mirv_cmd addAtTick 100 \"mirv_streams record start\";\n",
+	}
+	for path, content := range scripts {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write script: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := Run(context.Background(), []string{"lint", filepath.Join(dir, "cfg", "clips", "reel.cfg")}, log.New(&out, "", 0)); err != nil || !strings.Contains(out.String(), "lint that script separately") {
+		t.Fatalf("expected the stray part two folders up to be ignored, got %v:\n%s", err, out.String())
+	}
+}
@@ -0,0 +1,38 @@
+package bootstrap
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/atomicfile"
+)
+
+// addLockFlags registers the lock file options shared by the main run and
+// the render command.
+func addLockFlags(flags *flag.FlagSet, cfg *Config) {
+	flags.StringVar(&cfg.LockPath, "lock", "", "lock file held while the run writes, so runs sharing an output library take turns (empty disables)")
+	flags.DurationVar(&cfg.LockWait, "lock-wait", time.Minute, "how long to wait for another run to release --lock")
+}
+
+// acquireLock takes cfg's lock file, if any, logging while it waits. The
+// returned release is a no-op without one.
+func acquireLock(ctx context.Context, cfg Config, logger *log.Logger) (func(), error) {
+	if cfg.LockPath == "" {
+		return func() {}, nil
+	}
+	lock, err := atomicfile.Acquire(ctx, cfg.LockPath, 0)
+	if err != nil && cfg.LockWait > 0 {
+		logf(logger, "waiting up to %s for %s", cfg.LockWait, cfg.LockPath)
+		lock, err = atomicfile.Acquire(ctx, cfg.LockPath, cfg.LockWait)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := lock.Release(); err != nil {
+			logf(logger, "release %s: %v", cfg.LockPath, err)
+		}
+	}, nil
+}
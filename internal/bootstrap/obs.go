@@ -0,0 +1,54 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/obs"
+)
+
+// runOBS implements "highlighter obs [--url URL] [--password P] plan.obs.json":
+// it drives OBS through a plan written by an obs render target. The password
+// defaults to $OBS_WEBSOCKET_PASSWORD.
+func runOBS(ctx context.Context, args []string, logger *log.Logger) error {
+	driver := obs.NewDriver()
+	countdown := int(driver.Countdown / time.Second)
+	flags := flag.NewFlagSet("highlighter obs", flag.ContinueOnError)
+	flags.StringVar(&driver.URL, "url", driver.URL, "obs-websocket address")
+	flags.StringVar(&driver.Password, "password", os.Getenv("OBS_WEBSOCKET_PASSWORD"), "obs-websocket password (default $OBS_WEBSOCKET_PASSWORD)")
+	flags.IntVar(&countdown, "countdown", countdown, "seconds to switch to the game before pressing the resume key")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("obs needs one .obs.json plan path")
+	}
+	if countdown < 0 {
+		return errors.New("countdown must be >= 0")
+	}
+	driver.Countdown = time.Duration(countdown) * time.Second
+	driver.Logf = func(format string, args ...any) { logf(logger, format, args...) }
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var plan obs.Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return fmt.Errorf("parse plan %s: %w", flags.Arg(0), err)
+	}
+	if len(plan.Steps) == 0 {
+		return fmt.Errorf("plan %s has no segments to record", flags.Arg(0))
+	}
+	logf(logger, "%s: %d requests, playing %s from tick %d", flags.Arg(0), len(plan.Steps), plan.Demo, plan.StartTick)
+	return driver.Run(ctx, plan)
+}
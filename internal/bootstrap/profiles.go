@@ -0,0 +1,233 @@
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/recorder"
+)
+
+// renderSpec is a render target as given on the command line. Its options
+// are applied over the shared --hlae-* options once every flag is parsed, so
+// a target overrides them whatever the flag order.
+type renderSpec struct {
+	Target  hlae.Target
+	Options []renderOption
+}
+
+// renderOption is one key=value option of a render target or profile.
+type renderOption struct {
+	Key   string
+	Value string
+}
+
+// renderOptionSetter applies one option's value to a target and its options.
+type renderOptionSetter func(target *hlae.Target, options *hlae.Options, value string) error
+
+// renderOptionSetters holds every render option but profile, keyed like the
+// --hlae-* flag it overrides without the prefix. layers and recorder only
+// set the target.
+var renderOptionSetters = map[string]renderOptionSetter{
+	"layers": func(target *hlae.Target, _ *hlae.Options, value string) error {
+		layers, err := parseLayers(value)
+		target.Layers = layers
+		return err
+	},
+	"recorder": func(target *hlae.Target, _ *hlae.Options, value string) error {
+		rec, err := parseRecorder(value)
+		target.Recorder = rec
+		return err
+	},
+	"fps":      intOption(func(o *hlae.Options) *int { return &o.FrameRate }),
+	"path":     stringOption(func(o *hlae.Options) *string { return &o.OutputPath }),
+	"preset":   stringOption(func(o *hlae.Options) *string { return &o.FFmpegPreset }),
+	"preroll":  intOption(func(o *hlae.Options) *int { return &o.PreRollSeconds }),
+	"postroll": intOption(func(o *hlae.Options) *int { return &o.PostRollSeconds }),
+	"kill-gap": intOption(func(o *hlae.Options) *int { return &o.KillGapSeconds }),
+	"slowmo":   floatOption(func(o *hlae.Options) *float64 { return &o.SlowMotionScale }),
+	"slowmo-window": floatOption(func(o *hlae.Options) *float64 {
+		return &o.SlowMotionSeconds
+	}),
+	"replay": intOption(func(o *hlae.Options) *int { return &o.ReplaySeconds }),
+	"replay-types": func(_ *hlae.Target, options *hlae.Options, value string) error {
+		types, err := parseTypes(value)
+		options.ReplayTypes = types
+		return err
+	},
+	"intro": func(_ *hlae.Target, options *hlae.Options, value string) error {
+		types, err := parseIntroTypes(value)
+		options.IntroTypes = types
+		return err
+	},
+	"intro-style": func(_ *hlae.Target, options *hlae.Options, value string) error {
+		style, err := parseIntroStyle(value)
+		options.IntroStyle = style
+		return err
+	},
+	"intro-seconds": intOption(func(o *hlae.Options) *int { return &o.IntroSeconds }),
+	"max-lines":     intOption(func(o *hlae.Options) *int { return &o.MaxLines }),
+	"hud": func(_ *hlae.Target, options *hlae.Options, value string) error {
+		preset, err := parseHUDPreset(value)
+		options.HUD.Preset = preset
+		return err
+	},
+	"deathmsg-lifetime": floatOption(func(o *hlae.Options) *float64 { return &o.HUD.Lifetime }),
+	"deathmsg-color": func(_ *hlae.Target, options *hlae.Options, value string) error {
+		options.HUD.Color = strings.TrimPrefix(value, "#")
+		return nil
+	},
+	"hide-victims": func(_ *hlae.Target, options *hlae.Options, value string) error {
+		hide, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		options.HUD.HideVictims = hide
+		return nil
+	},
+}
+
+func parseRecorder(raw string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	if !slices.Contains(recorder.Names(), name) {
+		return "", fmt.Errorf("unknown recorder %q (valid: %s)", raw, strings.Join(recorder.Names(), ", "))
+	}
+	return name, nil
+}
+
+func intOption(field func(*hlae.Options) *int) renderOptionSetter {
+	return func(_ *hlae.Target, options *hlae.Options, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		*field(options) = parsed
+		return nil
+	}
+}
+
+func floatOption(field func(*hlae.Options) *float64) renderOptionSetter {
+	return func(_ *hlae.Target, options *hlae.Options, value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(options) = parsed
+		return nil
+	}
+}
+
+func stringOption(field func(*hlae.Options) *string) renderOptionSetter {
+	return func(_ *hlae.Target, options *hlae.Options, value string) error {
+		*field(options) = value
+		return nil
+	}
+}
+
+func renderOptionKeys() []string {
+	keys := []string{"profile"}
+	for key := range renderOptionSetters {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// parseRenderOption splits a key=value render option and checks the key.
+func parseRenderOption(raw string) (renderOption, error) {
+	key, value, ok := strings.Cut(raw, "=")
+	key = strings.ToLower(strings.TrimSpace(key))
+	if _, known := renderOptionSetters[key]; !ok || (!known && key != "profile") {
+		return renderOption{}, fmt.Errorf("unknown option %q (valid: %s)", raw, strings.Join(renderOptionKeys(), ", "))
+	}
+	return renderOption{Key: key, Value: strings.TrimSpace(value)}, nil
+}
+
+// resolveRenders applies each target's options over the shared options, left
+// to right, expanding profile=NAME in place so later options override the
+// profile. Targets that change no option beyond layers and recorder keep a
+// nil Options and follow the shared ones.
+func resolveRenders(specs []renderSpec, shared hlae.Options, profiles map[string][]renderOption) ([]hlae.Target, error) {
+	targets := make([]hlae.Target, 0, len(specs))
+	for _, spec := range specs {
+		target := spec.Target
+		options := shared
+		overridden := false
+		for _, option := range spec.Options {
+			applied := []renderOption{option}
+			if option.Key == "profile" {
+				profile, ok := profiles[option.Value]
+				if !ok {
+					return nil, fmt.Errorf("render target %q: unknown profile %q", target.Path, option.Value)
+				}
+				applied = profile
+			}
+			for _, option := range applied {
+				if err := renderOptionSetters[option.Key](&target, &options, option.Value); err != nil {
+					return nil, fmt.Errorf("render target %q: %s: %w", target.Path, option.Key, err)
+				}
+				overridden = overridden || (option.Key != "layers" && option.Key != "recorder")
+			}
+		}
+		if overridden {
+			options.OutputPath = strings.TrimSpace(options.OutputPath)
+			options.FFmpegPreset = strings.TrimSpace(options.FFmpegPreset)
+			target.Options = &options
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// profilesFile is the --profiles file: named sets of render options, written
+// with the same keys and values as a target's options, e.g.
+//
+//	{"profiles": {"slowmo": {"fps": 240, "slowmo": 0.3, "layers": "world,depth"}}}
+type profilesFile struct {
+	Profiles map[string]map[string]json.RawMessage `json:"profiles"`
+}
+
+// loadProfiles reads a --profiles file. An empty path loads no profiles.
+func loadProfiles(path string) (map[string][]renderOption, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read profiles: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var file profilesFile
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse profiles %s: %w", path, err)
+	}
+
+	profiles := make(map[string][]renderOption, len(file.Profiles))
+	for name, values := range file.Profiles {
+		options := make([]renderOption, 0, len(values))
+		for key, raw := range values {
+			value := strings.TrimSpace(string(raw))
+			var text string
+			if json.Unmarshal(raw, &text) == nil {
+				value = text
+			}
+			option, err := parseRenderOption(key + "=" + value)
+			if err != nil {
+				return nil, fmt.Errorf("profile %q: %w", name, err)
+			}
+			if option.Key == "profile" {
+				return nil, fmt.Errorf("profile %q: profiles cannot include other profiles", name)
+			}
+			options = append(options, option)
+		}
+		slices.SortFunc(options, func(a, b renderOption) int { return strings.Compare(a.Key, b.Key) })
+		profiles[name] = options
+	}
+	return profiles, nil
+}
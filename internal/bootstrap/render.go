@@ -0,0 +1,189 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/repository"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/repository/jsonrepo"
+)
+
+// renderConfig is the configuration of the render command: saved results,
+// narrowed by Filter, rendered with Config's render settings.
+type renderConfig struct {
+	// ResultPaths holds one saved result, or several whose render targets
+	// are chained like several --demo.
+	ResultPaths []string
+	Filter      highlightFilter
+	Config      Config
+}
+
+// highlightFilter keeps the highlights that match every field set: one of
+// Types, one of Rounds, one of Weapons and one of Indexes, the 1-based
+// positions in the saved result.
+type highlightFilter struct {
+	Types   model.Selection
+	Rounds  map[int]bool
+	Weapons map[string]bool
+	Indexes map[int]bool
+}
+
+// runRender implements "highlighter render [flags] highlights.json...": it
+// re-renders saved results with new targets and --hlae-* options, without
+// parsing their demos again.
+func runRender(ctx context.Context, args []string, logger *log.Logger) error {
+	cfg, err := parseRenderConfig(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	results := make([]model.HighlightResult, 0, len(cfg.ResultPaths))
+	for _, path := range cfg.ResultPaths {
+		result, err := jsonrepo.NewWithFormat(path, repository.FormatJSON).Load(ctx)
+		if err != nil {
+			return err
+		}
+		total := len(result.Highlights)
+		result = cfg.Filter.apply(result)
+		logf(logger, "%s: rendering %d of %d highlights", path, len(result.Highlights), total)
+		results = append(results, result)
+	}
+
+	release, err := acquireLock(ctx, cfg.Config, logger)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return writeRecordings(cfg.Config, results, logger)
+}
+
+func parseRenderConfig(args []string) (renderConfig, error) {
+	cfg := renderConfig{Config: defaultConfig()}
+
+	var (
+		typesRaw   string
+		roundsRaw  string
+		weaponsRaw string
+		indexRaw   string
+		render     renderFlags
+	)
+
+	flags := flag.NewFlagSet("highlighter render", flag.ContinueOnError)
+	flags.StringVar(&typesRaw, "types", "", "comma-separated highlight types rendered (empty = all): "+strings.Join(formatNames(model.AllHighlightTypes()), ","))
+	flags.StringVar(&roundsRaw, "rounds", "", "comma-separated rounds or ranges rendered, e.g. 3,10-14 (empty = all)")
+	flags.StringVar(&weaponsRaw, "weapons", "", "comma-separated weapons rendered, e.g. awp,ak47 (empty = all)")
+	flags.StringVar(&indexRaw, "index", "", "comma-separated 1-based positions in the saved highlights, or ranges, e.g. 1,4-6 (empty = all)")
+	addRenderFlags(flags, &cfg.Config, &render)
+	addLockFlags(flags, &cfg.Config)
+
+	if err := flags.Parse(args); err != nil {
+		return renderConfig{}, err
+	}
+	if flags.NArg() == 0 {
+		return renderConfig{}, errors.New("render needs at least one highlights .json path")
+	}
+	for _, path := range flags.Args() {
+		cfg.ResultPaths = append(cfg.ResultPaths, strings.TrimSpace(path))
+	}
+	cfg.Config.normalize()
+
+	types, err := parseTypes(typesRaw)
+	if err != nil {
+		return renderConfig{}, err
+	}
+	cfg.Filter.Types = types
+	if cfg.Filter.Rounds, err = parseIntSet("rounds", roundsRaw); err != nil {
+		return renderConfig{}, err
+	}
+	if cfg.Filter.Indexes, err = parseIntSet("index", indexRaw); err != nil {
+		return renderConfig{}, err
+	}
+	cfg.Filter.Weapons = parseWeapons(weaponsRaw)
+
+	if err := render.apply(&cfg.Config); err != nil {
+		return renderConfig{}, err
+	}
+	if err := cfg.Config.validateRender(); err != nil {
+		return renderConfig{}, err
+	}
+	return cfg, nil
+}
+
+// parseIntSet turns "3,10-14" into the set of numbers it lists, each at
+// least 1. Empty input yields nil, which matches every number.
+func parseIntSet(flagName, raw string) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, token := range strings.Split(raw, ",") {
+		item := strings.TrimSpace(token)
+		if item == "" {
+			continue
+		}
+		fromRaw, toRaw, isRange := strings.Cut(item, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(fromRaw))
+		to := from
+		if err == nil && isRange {
+			to, err = strconv.Atoi(strings.TrimSpace(toRaw))
+		}
+		if err != nil || from < 1 || to < from {
+			return nil, fmt.Errorf("%s: %q must be a number or a range like 3-5, from 1 up", flagName, item)
+		}
+		for n := from; n <= to; n++ {
+			set[n] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil, nil
+	}
+	return set, nil
+}
+
+// parseWeapons turns "AWP,ak-47" into weapon keys. Empty input yields nil,
+// which matches every weapon.
+func parseWeapons(raw string) map[string]bool {
+	weapons := make(map[string]bool)
+	for _, token := range strings.Split(raw, ",") {
+		if key := weaponKey(token); key != "" {
+			weapons[key] = true
+		}
+	}
+	if len(weapons) == 0 {
+		return nil
+	}
+	return weapons
+}
+
+// weaponKey compares weapon names by their letters and digits alone, so
+// "ak47" matches the AK-47 and "desert eagle" the Desert Eagle.
+func weaponKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// apply returns result with only the highlights f keeps.
+func (f highlightFilter) apply(result model.HighlightResult) model.HighlightResult {
+	kept := make([]model.Highlight, 0, len(result.Highlights))
+	for i, h := range result.Highlights {
+		if !f.Types.Enabled(h.Type) ||
+			(f.Rounds != nil && !f.Rounds[h.Round]) ||
+			(f.Weapons != nil && !f.Weapons[weaponKey(h.Weapon)]) ||
+			(f.Indexes != nil && !f.Indexes[i+1]) {
+			continue
+		}
+		kept = append(kept, h)
+	}
+	result.Highlights = kept
+	return result
+}
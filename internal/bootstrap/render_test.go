@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/atomicfile"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/repository/jsonrepo"
+)
+
+func TestRenderFiltersSavedHighlights(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	resultPath := filepath.Join(tempDir, "highlights.json")
+	result := model.HighlightResult{Demo: "mirage.dem", SteamID: "76561197960265728", TickRate: 64, Highlights: []model.Highlight{
+		{Type: model.HighlightHeadshot, Round: 3, Weapon: "AK-47", PlayerSlot: 7, TickStart: 1000, TickEnd: 1000, SegmentFrom: 1000, SegmentTo: 1000},
+		{Type: model.HighlightNoScope, Round: 5, Weapon: "AWP", PlayerSlot: 7, TickStart: 5000, TickEnd: 5000, SegmentFrom: 5000, SegmentTo: 5000},
+		{Type: model.HighlightHeadshot, Round: 9, Weapon: "AK-47", PlayerSlot: 7, TickStart: 9000, TickEnd: 9000, SegmentFrom: 9000, SegmentTo: 9000},
+	}}
+	if err := jsonrepo.New(resultPath).Save(context.Background(), result); err != nil {
+		t.Fatalf("save result: %v", err)
+	}
+
+	target := filepath.Join(tempDir, "out", "ak.cfg")
+	args := []string{"render", "--weapons", "ak47", "--rounds", "1-4,9", "--index", "1", "--hlae-preroll", "1", "--montage", target, resultPath}
+	if err := Run(context.Background(), args, nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	data, err := os.ReadFile(hlae.ManifestPath(target))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	var manifest hlae.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("parse manifest: %v", err)
+	}
+	if len(manifest.Recordings) != 1 || len(manifest.Recordings[0].Highlights) != 1 {
+		t.Fatalf("expected the one highlight matching every filter, got %+v", manifest.Recordings)
+	}
+	if h := manifest.Recordings[0].Highlights[0]; h.Round != 3 || h.PreRoll != 1 {
+		t.Fatalf("expected round 3 with a one second pre-roll, got %+v", h)
+	}
+
+	for _, bad := range []string{"0", "5-3", "x"} {
+		if _, err := parseRenderConfig([]string{"--rounds", bad, resultPath}); err == nil || !strings.Contains(err.Error(), "rounds") {
+			t.Fatalf("%s: expected a rounds error, got %v", bad, err)
+		}
+	}
+	if _, err := parseRenderConfig(nil); err == nil {
+		t.Fatalf("expected an error without result paths")
+	}
+}
+
+func TestRenderTakesTheLockFile(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	resultPath := filepath.Join(tempDir, "highlights.json")
+	if err := jsonrepo.New(resultPath).Save(context.Background(), model.HighlightResult{Demo: "mirage.dem", TickRate: 64}); err != nil {
+		t.Fatalf("save result: %v", err)
+	}
+	lockPath := filepath.Join(tempDir, "library.lock")
+	args := []string{"render", "--lock", lockPath, "--lock-wait", "0", "--clips", filepath.Join(tempDir, "clips.cfg"), resultPath}
+
+	lock, err := atomicfile.Acquire(context.Background(), lockPath, 0)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if err := Run(context.Background(), args, nil); err == nil || !strings.Contains(err.Error(), "held") {
+		t.Fatalf("expected the held lock to stop the run, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "clips.cfg")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written without the lock, got %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err := Run(context.Background(), args, nil); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock released after the run, got %v", err)
+	}
+}
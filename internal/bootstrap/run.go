@@ -2,19 +2,40 @@ package bootstrap
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/eSheikh/cs2-demo-highlighter/internal/engine"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/parser/demoinfocs"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/publish"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/recorder"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/repository/jsonrepo"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/service"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/timeline"
 )
 
 func Run(ctx context.Context, args []string, logger *log.Logger) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "lint":
+			return runLint(args[1:], logger)
+		case "obs":
+			return runOBS(ctx, args[1:], logger)
+		case "ffmpeg":
+			return runFFmpeg(ctx, args[1:], logger)
+		case "render":
+			return runRender(ctx, args[1:], logger)
+		}
+	}
+
 	cfg, err := ParseConfig(args)
 	if err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -23,41 +44,163 @@ func Run(ctx context.Context, args []string, logger *log.Logger) error {
 		return err
 	}
 
+	highlights := service.NewHighlightService()
+	highlights.StreakRules = cfg.Streaks
+	highlights.LowHPThreshold = cfg.LowHP
+
 	eng := engine.New(
 		demoinfocs.NewParser(),
-		service.NewHighlightService(),
+		highlights,
 	)
 
-	result, err := eng.Extract(ctx, engine.ExtractOptions{
-		DemoPath: cfg.DemoPath,
-		SteamID:  cfg.SteamID,
-		Types:    cfg.Types,
-	})
+	results := make([]model.HighlightResult, 0, len(cfg.DemoPaths))
+	for _, demoPath := range cfg.DemoPaths {
+		result, err := eng.Extract(ctx, engine.ExtractOptions{
+			DemoPath:    demoPath,
+			SteamID:     cfg.SteamID,
+			Types:       cfg.Types,
+			Perspective: cfg.Perspective,
+			AllPlayers:  cfg.Team,
+			Top:         cfg.Top,
+		})
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	// Parsing only reads, so the lock is held from the first write on.
+	release, err := acquireLock(ctx, cfg, logger)
 	if err != nil {
 		return err
 	}
+	defer release()
 
-	if err := jsonrepo.New(cfg.OutputPath).Save(ctx, result); err != nil {
-		return err
+	for i, result := range results {
+		outputPath := resultOutputPath(cfg.OutputPath, i, cfg.DemoPaths[i], len(cfg.DemoPaths))
+		if err := jsonrepo.NewWithFormat(outputPath, cfg.OutFormat).Save(ctx, result); err != nil {
+			return err
+		}
+		logOutputSaved(logger, outputPath)
 	}
-	logOutputSaved(logger, cfg.OutputPath)
 
-	if err := writeHLAEScripts(cfg, result, logger); err != nil {
+	if err := writeRecordings(cfg, results, logger); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func writeHLAEScripts(cfg Config, result model.HighlightResult, logger *log.Logger) error {
+// resultOutputPath is where the result of the i-th of count demos is saved:
+// the --out path itself for a single demo, otherwise that path with the
+// demo's position and name added, e.g. highlights.02_inferno.json.
+func resultOutputPath(outputPath string, i int, demoPath string, count int) string {
+	if count <= 1 || outputPath == "" {
+		return outputPath
+	}
+	demo := strings.TrimSuffix(filepath.Base(demoPath), filepath.Ext(demoPath))
+	ext := filepath.Ext(outputPath)
+	return fmt.Sprintf("%s.%02d_%s%s", strings.TrimSuffix(outputPath, ext), i+1, demo, ext)
+}
+
+// writeRecordings renders every target with its recorder over the demo's
+// result, or chains it over the results of several demos.
+func writeRecordings(cfg Config, results []model.HighlightResult, logger *log.Logger) error {
 	for _, target := range cfg.Renders {
-		if err := writeHLAEScriptFile(target.Path, hlae.BuildTarget(result, cfg.HLAE, target), logger); err != nil {
+		files, err := recorder.Render(results, cfg.HLAE, target)
+		if err != nil {
+			return err
+		}
+		timelines, err := timelineFiles(target, files, cfg.Timelines)
+		if err != nil {
+			return err
+		}
+		files = append(files, timelines...)
+		subtitles, err := captionFiles(target, files, cfg.Captions, &cfg.Captioner)
+		if err != nil {
 			return err
 		}
+		files = append(files, subtitles...)
+		if cfg.Descriptions {
+			writer := &publish.Writer{Captions: cfg.Captioner}
+			description, err := descriptionFiles(target, files, results, writer, logger)
+			if err != nil {
+				return err
+			}
+			files = append(files, description...)
+		}
+		if cfg.FFmpegScripts {
+			jobFiles, err := ffmpegFiles(target, files, &cfg.FFmpeg)
+			if err != nil {
+				return err
+			}
+			files = append(files, jobFiles...)
+		}
+		for _, file := range files {
+			if err := writeHLAEScriptFile(file.Path, file.Content, logger); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// timelineFiles exports the target's manifest, found among its rendered
+// files, as each timeline format. Recorders that write no manifest get no
+// timelines.
+func timelineFiles(target hlae.Target, files []hlae.File, formats []timeline.Format) ([]hlae.File, error) {
+	if len(formats) == 0 {
+		return nil, nil
+	}
+	manifest, ok, err := targetManifest(target, files)
+	if err != nil || !ok {
+		return nil, err
+	}
+	exporter := timeline.NewExporter()
+	out := make([]hlae.File, 0, len(formats))
+	for _, format := range formats {
+		content, err := exporter.Export(manifest, format)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, hlae.File{Path: timeline.Path(target.Path, format), Content: content})
+	}
+	return out, nil
+}
+
+// descriptionFiles describes the target's manifest over the results it was
+// rendered from. Recorders that write no manifest get no description, and
+// montages whose chapters YouTube would not read get no chapters.
+func descriptionFiles(target hlae.Target, files []hlae.File, results []model.HighlightResult, writer *publish.Writer, logger *log.Logger) ([]hlae.File, error) {
+	manifest, ok, err := targetManifest(target, files)
+	if err != nil || !ok {
+		return nil, err
+	}
+	chapters := writer.Chapters(manifest)
+	if len(chapters) > 0 {
+		if err := publish.CheckChapters(chapters); err != nil {
+			logf(logger, "%s: skipping chapters: %v", target.Path, err)
+			chapters = nil
+		}
+	}
+	return writer.Files(manifest, results, chapters, target.Path), nil
+}
+
+// targetManifest decodes the target's manifest from its rendered files; ok
+// is false when its recorder wrote none.
+func targetManifest(target hlae.Target, files []hlae.File) (hlae.Manifest, bool, error) {
+	manifestPath := hlae.ManifestPath(target.Path)
+	index := slices.IndexFunc(files, func(file hlae.File) bool { return file.Path == manifestPath })
+	if index < 0 {
+		return hlae.Manifest{}, false, nil
+	}
+	var manifest hlae.Manifest
+	if err := json.Unmarshal([]byte(files[index].Content), &manifest); err != nil {
+		return hlae.Manifest{}, false, fmt.Errorf("read manifest %s: %w", manifestPath, err)
+	}
+	return manifest, true, nil
+}
+
 func writeHLAEScriptFile(path string, content string, logger *log.Logger) error {
 	if err := writeTextFile(path, content); err != nil {
 		return err
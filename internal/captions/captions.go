@@ -0,0 +1,289 @@
+// Package captions writes on-screen captions for recorded highlights, such
+// as "Round 14 — 1v3 clutch — AK-47", as SRT or ASS subtitles timed from a
+// recording manifest: against the whole montage, or against each clip.
+package captions
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// Format is a subtitle file format.
+type Format string
+
+const (
+	FormatSRT Format = "srt"
+	FormatASS Format = "ass"
+)
+
+func AllFormats() []Format {
+	return []Format{FormatSRT, FormatASS}
+}
+
+// Ext is the file extension of the format, with the dot.
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// Captioner captions highlights. Templates override Template per highlight
+// type. A caption shows from Lead seconds before the highlight's first kill
+// to Hold seconds after its last.
+type Captioner struct {
+	Template  Template
+	Templates map[model.HighlightType]Template
+	Lead      float64
+	Hold      float64
+}
+
+func NewCaptioner() *Captioner {
+	return &Captioner{
+		Template: MustParseTemplate(DefaultTemplate),
+		Lead:     1,
+		Hold:     2,
+	}
+}
+
+// Cue is one caption, in seconds of the video it is timed against.
+type Cue struct {
+	Start float64
+	End   float64
+	Text  string
+}
+
+// Text is the caption of a highlight of a recording in manifest.
+func (c *Captioner) Text(manifest hlae.Manifest, recording hlae.ManifestRecording, h hlae.ManifestHighlight) string {
+	template, ok := c.Templates[h.Type]
+	if !ok {
+		template = c.Template
+	}
+	demo := recording.Demo
+	if demo == "" {
+		demo = manifest.Demo
+	}
+	return template.Render(Values(h, demo))
+}
+
+// Values are the placeholder values of a highlight.
+func Values(h hlae.ManifestHighlight, demo string) map[string]string {
+	kills := h.Kills
+	if kills == 0 {
+		kills = len(h.KillTicks)
+	}
+	if demo != "" {
+		demo = path.Base(strings.ReplaceAll(demo, `\`, "/"))
+		demo = strings.TrimSuffix(demo, path.Ext(demo))
+	}
+	return map[string]string{
+		"round":   strconv.Itoa(h.Round),
+		"type":    string(h.Type),
+		"label":   Label(h),
+		"weapon":  h.Weapon,
+		"victims": strings.Join(nonEmpty(h.Victims), ", "),
+		"kills":   strconv.Itoa(kills),
+		"clutch":  h.Meta["clutch"],
+		"streak":  h.Meta["streak"],
+		"killer":  h.Meta["killer_name"],
+		"demo":    demo,
+	}
+}
+
+// Label names what a highlight is: "1v3 clutch", "4K", "ace", "wallbang".
+func Label(h hlae.ManifestHighlight) string {
+	switch h.Type {
+	case model.HighlightClutchWin:
+		if clutch := h.Meta["clutch"]; clutch != "" {
+			return clutch + " clutch"
+		}
+		return "clutch"
+	case model.HighlightMultiKill:
+		kills := h.Kills
+		if kills == 0 {
+			kills = len(h.KillTicks)
+		}
+		if kills >= 5 {
+			return "ace"
+		}
+		return fmt.Sprintf("%dK", kills)
+	case model.HighlightKillStreak:
+		if streak := h.Meta["streak"]; streak != "" {
+			return "kill streak (" + streak + ")"
+		}
+	case model.HighlightDeath:
+		if killer := h.Meta["killer_name"]; killer != "" {
+			return "killed by " + killer
+		}
+	}
+	if label, ok := typeLabels[h.Type]; ok {
+		return label
+	}
+	return strings.ReplaceAll(string(h.Type), "_", " ")
+}
+
+var typeLabels = map[model.HighlightType]string{
+	model.HighlightKillInSmoke: "smoke kill",
+	model.HighlightKillBlinded: "blind kill",
+	model.HighlightWallbang:    "wallbang",
+	model.HighlightNoScope:     "noscope",
+	model.HighlightHeadshot:    "headshot",
+	model.HighlightLowHPKill:   "low HP kill",
+	model.HighlightKillStreak:  "kill streak",
+	model.HighlightDeath:       "death",
+}
+
+func nonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Cues captions the highlights of one recording, offset seconds into the
+// video they are timed against.
+func (c *Captioner) Cues(manifest hlae.Manifest, recording hlae.ManifestRecording, offset float64) []Cue {
+	var cues []Cue
+	for _, h := range recording.Highlights {
+		if len(h.KillTimes) == 0 {
+			continue
+		}
+		text := c.Text(manifest, recording, h)
+		if text == "" {
+			continue
+		}
+		start := max(0, h.KillTimes[0]-c.Lead)
+		end := min(recording.Duration, h.KillTimes[len(h.KillTimes)-1]+c.Hold)
+		if end <= start {
+			continue
+		}
+		cues = append(cues, Cue{Start: offset + start, End: offset + end, Text: text})
+	}
+	return tidy(cues)
+}
+
+// tidy orders cues and keeps them from overlapping: cues that start
+// together are shown as one, and a cue ends where the next starts.
+func tidy(cues []Cue) []Cue {
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+	var out []Cue
+	for _, cue := range cues {
+		if n := len(out); n > 0 {
+			prev := &out[n-1]
+			if cue.Start == prev.Start {
+				if !strings.Contains(prev.Text, cue.Text) {
+					prev.Text += "\n" + cue.Text
+				}
+				prev.End = max(prev.End, cue.End)
+				continue
+			}
+			if cue.Start < prev.End {
+				prev.End = cue.Start
+			}
+		}
+		out = append(out, cue)
+	}
+	return out
+}
+
+// Files writes the captions of the script at scriptPath in each format. A
+// montage gets <target>.srt timed against its recordings back to back;
+// clips get <target>.captions/NNN_<take>.srt per recording.
+func (c *Captioner) Files(manifest hlae.Manifest, scriptPath string, formats []Format) []hlae.File {
+	base := strings.TrimSuffix(scriptPath, filepath.Ext(scriptPath))
+	var files []hlae.File
+	if manifest.Mode == hlae.ModeMontage.String() {
+		var cues []Cue
+		offset := 0.0
+		for _, recording := range manifest.Recordings {
+			cues = append(cues, c.Cues(manifest, recording, offset)...)
+			offset += recording.Duration
+		}
+		for _, format := range formats {
+			files = append(files, hlae.File{Path: base + format.Ext(), Content: Render(tidy(cues), format)})
+		}
+		return files
+	}
+	for i, recording := range manifest.Recordings {
+		cues := c.Cues(manifest, recording, 0)
+		if len(cues) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("%03d_%s", i+1, takeName(recording.Folder))
+		for _, format := range formats {
+			files = append(files, hlae.File{Path: filepath.Join(base+".captions", name+format.Ext()), Content: Render(cues, format)})
+		}
+	}
+	return files
+}
+
+// takeName is a take's own folder name, or its clip's for named takes.
+func takeName(folder string) string {
+	name := path.Base(folder)
+	if dir := path.Dir(folder); name == "take0000" && dir != "." {
+		name = path.Base(dir)
+	}
+	return name
+}
+
+// Render writes cues in format.
+func Render(cues []Cue, format Format) string {
+	if format == FormatASS {
+		return renderASS(cues)
+	}
+	return renderSRT(cues)
+}
+
+func renderSRT(cues []Cue) string {
+	var sb strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, srtTime(cue.Start), srtTime(cue.End), cue.Text)
+	}
+	return sb.String()
+}
+
+// assHeader styles captions white with a black outline, centred near the
+// bottom of a 1080p frame; players scale it to the video.
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,56,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,-1,0,0,0,100,100,0,0,1,3,1,2,60,60,80,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+func renderASS(cues []Cue) string {
+	var sb strings.Builder
+	sb.WriteString(assHeader)
+	escape := strings.NewReplacer("{", `\{`, "}", `\}`, "\n", `\N`)
+	for _, cue := range cues {
+		fmt.Fprintf(&sb, "Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n", assTime(cue.Start), assTime(cue.End), escape.Replace(cue.Text))
+	}
+	return sb.String()
+}
+
+// srtTime formats seconds as HH:MM:SS,mmm.
+func srtTime(seconds float64) string {
+	ms := int(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// assTime formats seconds as H:MM:SS.cc.
+func assTime(seconds float64) string {
+	cs := int(seconds*100 + 0.5)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
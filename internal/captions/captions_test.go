@@ -0,0 +1,109 @@
+package captions
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+func testManifest(mode string) hlae.Manifest {
+	return hlae.Manifest{
+		Demo: "/demos/mirage.dem",
+		Mode: mode,
+		Recordings: []hlae.ManifestRecording{
+			{
+				Folder:   "/rec/7656/r14_clutch_win_9000/take0000",
+				Duration: 8,
+				Highlights: []hlae.ManifestHighlight{
+					{Type: model.HighlightClutchWin, Round: 14, KillTimes: []float64{3, 5.5}, Kills: 3, Weapon: "AK-47", Meta: map[string]string{"clutch": "1v3"}},
+					{Type: model.HighlightMultiKill, Round: 14, KillTimes: []float64{3, 5.5}, Kills: 3, Weapon: "AK-47"},
+				},
+			},
+			{
+				Folder:   "/rec/7656/r20_headshot_kill_12000/take0000",
+				Duration: 5,
+				Highlights: []hlae.ManifestHighlight{
+					{Type: model.HighlightHeadshot, Round: 20, KillTimes: []float64{3}, Victims: []string{"s1mple", " "}},
+				},
+			},
+		},
+	}
+}
+
+func TestTemplateOptionalGroups(t *testing.T) {
+	template, err := ParseTemplate("R{round} {label}[ with {weapon}][ vs {victims}]")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	got := template.Render(map[string]string{"round": "3", "label": "headshot", "victims": "s1mple"})
+	if got != "R3 headshot vs s1mple" {
+		t.Fatalf("expected the empty weapon group dropped, got %q", got)
+	}
+	for _, raw := range []string{"{map}", "{round", "round}", "[a[b]]", "[{round}", "a]"} {
+		if _, err := ParseTemplate(raw); err == nil {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestLabelNamesHighlights(t *testing.T) {
+	cases := map[string]hlae.ManifestHighlight{
+		"1v3 clutch":             {Type: model.HighlightClutchWin, Meta: map[string]string{"clutch": "1v3"}},
+		"4K":                     {Type: model.HighlightMultiKill, KillTicks: []int{1, 2, 3, 4}},
+		"ace":                    {Type: model.HighlightMultiKill, Kills: 5},
+		"kill streak (3 rounds)": {Type: model.HighlightKillStreak, Meta: map[string]string{"streak": "3 rounds"}},
+		"killed by enemy":        {Type: model.HighlightDeath, Meta: map[string]string{"killer_name": "enemy"}},
+		"smoke kill":             {Type: model.HighlightKillInSmoke},
+	}
+	for want, h := range cases {
+		if got := Label(h); got != want {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestFilesTimeMontageBackToBack(t *testing.T) {
+	captioner := NewCaptioner()
+	captioner.Templates = map[model.HighlightType]Template{
+		model.HighlightHeadshot: MustParseTemplate("{demo}: {label} on {victims}"),
+	}
+	files := captioner.Files(testManifest("montage"), "out/reel.cfg", AllFormats())
+	if len(files) != 2 || files[0].Path != "out/reel.srt" || files[1].Path != "out/reel.ass" {
+		t.Fatalf("expected montage srt and ass, got %+v", files)
+	}
+	wantSRT := "1\n00:00:02,000 --> 00:00:07,500\nRound 14 — 1v3 clutch — AK-47\nRound 14 — 3K — AK-47\n\n" +
+		"2\n00:00:10,000 --> 00:00:13,000\nmirage: headshot on s1mple\n\n"
+	if files[0].Content != wantSRT {
+		t.Fatalf("unexpected srt:\n%s", files[0].Content)
+	}
+	if !strings.Contains(files[1].Content, "Dialogue: 0,0:00:02.00,0:00:07.50,Default,,0,0,0,,Round 14 — 1v3 clutch — AK-47\\NRound 14 — 3K — AK-47\n") {
+		t.Fatalf("unexpected ass:\n%s", files[1].Content)
+	}
+}
+
+func TestFilesCaptionEachClip(t *testing.T) {
+	files := NewCaptioner().Files(testManifest("clips"), "out/clips.cfg", []Format{FormatSRT})
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, filepath.ToSlash(file.Path))
+	}
+	want := []string{"out/clips.captions/001_r14_clutch_win_9000.srt", "out/clips.captions/002_r20_headshot_kill_12000.srt"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+	if !strings.HasPrefix(files[1].Content, "1\n00:00:02,000 --> 00:00:05,000\nRound 20 — headshot\n") {
+		t.Fatalf("expected clip-relative timing, got:\n%s", files[1].Content)
+	}
+}
+
+func TestTidyTruncatesOverlaps(t *testing.T) {
+	got := tidy([]Cue{{Start: 4, End: 9, Text: "b"}, {Start: 1, End: 6, Text: "a"}})
+	want := []Cue{{Start: 1, End: 4, Text: "a"}, {Start: 4, End: 9, Text: "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,131 @@
+package captions
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Placeholders a template can use, each replaced by a highlight's value.
+var placeholders = []string{"round", "type", "label", "weapon", "victims", "kills", "clutch", "streak", "killer", "demo"}
+
+func Placeholders() []string {
+	return slices.Clone(placeholders)
+}
+
+// Template is a caption with {placeholder} fields. Text in [brackets] is an
+// optional group, dropped when any placeholder in it is empty.
+type Template struct {
+	raw   string
+	nodes []node
+}
+
+// node is literal text, a placeholder, or an optional group of both.
+type node struct {
+	text        string
+	placeholder string
+	group       []node
+}
+
+// DefaultTemplate reads like "Round 14 — 1v3 clutch — AK-47".
+const DefaultTemplate = "Round {round} — {label}[ — {weapon}]"
+
+// ParseTemplate checks raw's placeholders and groups.
+func ParseTemplate(raw string) (Template, error) {
+	var top, group []node
+	inGroup := false
+	add := func(n node) {
+		if inGroup {
+			group = append(group, n)
+		} else {
+			top = append(top, n)
+		}
+	}
+	rest := raw
+	for rest != "" {
+		i := strings.IndexAny(rest, "{}[]")
+		if i < 0 {
+			add(node{text: rest})
+			break
+		}
+		if i > 0 {
+			add(node{text: rest[:i]})
+		}
+		switch rest[i] {
+		case '{':
+			end := strings.IndexByte(rest[i:], '}')
+			if end < 0 {
+				return Template{}, fmt.Errorf("caption template %q: unclosed {", raw)
+			}
+			name := rest[i+1 : i+end]
+			if !slices.Contains(placeholders, name) {
+				return Template{}, fmt.Errorf("caption template %q: unknown placeholder {%s} (valid: %s)", raw, name, strings.Join(placeholders, ", "))
+			}
+			add(node{placeholder: name})
+			rest = rest[i+end+1:]
+			continue
+		case '[':
+			if inGroup {
+				return Template{}, fmt.Errorf("caption template %q: groups cannot nest", raw)
+			}
+			inGroup, group = true, nil
+		case ']':
+			if !inGroup {
+				return Template{}, fmt.Errorf("caption template %q: ] without [", raw)
+			}
+			inGroup = false
+			top = append(top, node{group: group})
+		case '}':
+			return Template{}, fmt.Errorf("caption template %q: } without {", raw)
+		}
+		rest = rest[i+1:]
+	}
+	if inGroup {
+		return Template{}, fmt.Errorf("caption template %q: unclosed [", raw)
+	}
+	return Template{raw: raw, nodes: top}, nil
+}
+
+// MustParseTemplate is ParseTemplate for templates known to be valid.
+func MustParseTemplate(raw string) Template {
+	t, err := ParseTemplate(raw)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t Template) String() string {
+	return t.raw
+}
+
+// Render fills the template from values.
+func (t Template) Render(values map[string]string) string {
+	var sb strings.Builder
+	for _, n := range t.nodes {
+		if n.group == nil {
+			sb.WriteString(n.value(values))
+			continue
+		}
+		complete := true
+		for _, member := range n.group {
+			if member.placeholder != "" && values[member.placeholder] == "" {
+				complete = false
+			}
+		}
+		if !complete {
+			continue
+		}
+		for _, member := range n.group {
+			sb.WriteString(member.value(values))
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func (n node) value(values map[string]string) string {
+	if n.placeholder != "" {
+		return values[n.placeholder]
+	}
+	return n.text
+}
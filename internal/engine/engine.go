@@ -15,7 +15,10 @@ type DemoParser interface {
 }
 
 type HighlightBuilder interface {
-	BuildHighlights(demo string, steamID string, tickRate float64, kills []model.KillEvent, selection model.Selection) model.HighlightResult
+	BuildHighlights(parsed model.ParsedDemo, steamID string, selection model.Selection) model.HighlightResult
+	BuildDeathHighlights(parsed model.ParsedDemo, steamID string, selection model.Selection) model.HighlightResult
+	BuildTeamHighlights(parsed model.ParsedDemo, selection model.Selection, top int) model.HighlightResult
+	BuildStats(parsed model.ParsedDemo, steamID string) *model.PlayerStats
 }
 
 // Progress reports parsing advancement as a 0..1 fraction.
@@ -24,13 +27,19 @@ type Progress struct {
 }
 
 // ExtractOptions configures a single extraction run. Types selects which
-// highlight types to keep (empty = all). Progress, if non-nil, receives updates
-// as parsing advances and is closed on return.
+// highlight types to keep (empty = all). Perspective picks the player's kills
+// (empty = kills) or deaths. AllPlayers ignores SteamID and ranks the kill
+// highlights of every player in one result, keeping the best Top (0 = all).
+// Progress, if non-nil, receives updates as parsing advances and is closed on
+// return.
 type ExtractOptions struct {
-	DemoPath string
-	SteamID  string
-	Types    model.Selection
-	Progress chan<- Progress
+	DemoPath    string
+	SteamID     string
+	Types       model.Selection
+	Perspective model.Perspective
+	AllPlayers  bool
+	Top         int
+	Progress    chan<- Progress
 }
 
 type Engine struct {
@@ -47,7 +56,8 @@ func (e *Engine) Roster(ctx context.Context, demoPath string) ([]model.Player, e
 	return e.parser.Roster(ctx, demoPath)
 }
 
-// Extract parses the demo and builds the selected highlights for opts.SteamID.
+// Extract parses the demo and builds the selected highlights for opts.SteamID
+// from the requested perspective, together with the player's stats.
 // If opts.Progress is non-nil it receives updates as parsing advances and is
 // closed on return; the send is non-blocking, so a slow consumer only drops
 // intermediate updates.
@@ -66,10 +76,25 @@ func (e *Engine) Extract(ctx context.Context, opts ExtractOptions) (model.Highli
 		}
 	}
 
-	parsed, err := e.parser.Parse(ctx, opts.DemoPath, opts.SteamID, onProgress)
+	steamID := opts.SteamID
+	if opts.AllPlayers {
+		steamID = ""
+	}
+
+	parsed, err := e.parser.Parse(ctx, opts.DemoPath, steamID, onProgress)
 	if err != nil {
 		return model.HighlightResult{}, err
 	}
 
-	return e.builder.BuildHighlights(parsed.Demo, opts.SteamID, parsed.TickRate, parsed.Kills, opts.Types), nil
+	if opts.AllPlayers {
+		return e.builder.BuildTeamHighlights(parsed, opts.Types, opts.Top), nil
+	}
+	var result model.HighlightResult
+	if opts.Perspective == model.PerspectiveDeaths {
+		result = e.builder.BuildDeathHighlights(parsed, opts.SteamID, opts.Types)
+	} else {
+		result = e.builder.BuildHighlights(parsed, opts.SteamID, opts.Types)
+	}
+	result.Stats = e.builder.BuildStats(parsed, opts.SteamID)
+	return result, nil
 }
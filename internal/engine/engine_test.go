@@ -37,8 +37,9 @@ func TestExtractBuildsHighlightsAndStreamsProgress(t *testing.T) {
 			Demo:     "match.dem",
 			TickRate: 64,
 			Kills: []model.KillEvent{
-				{Tick: 100, Round: 1, VictimID: "v1", KillerSlot: 7, IsWallbang: true},
+				{Tick: 100, Round: 1, KillerID: "steam", VictimID: "v1", KillerSlot: 7, IsWallbang: true},
 			},
+			Rounds: []model.RoundInfo{{Number: 1, Winner: "CT"}},
 		},
 		fractions: []float64{0.5, 1},
 	}
@@ -57,6 +58,9 @@ func TestExtractBuildsHighlightsAndStreamsProgress(t *testing.T) {
 	if result.SteamID != "steam" || result.TickRate != 64 {
 		t.Fatalf("unexpected metadata: %+v", result)
 	}
+	if result.Stats == nil || result.Stats.Totals.Kills != 1 || len(result.Stats.Rounds) != 1 {
+		t.Fatalf("expected stats from the same parse, got %+v", result.Stats)
+	}
 
 	got := make([]float64, 0, 2)
 	for p := range progress {
@@ -137,3 +141,34 @@ func TestRosterDelegates(t *testing.T) {
 		t.Fatalf("unexpected roster: %+v", got)
 	}
 }
+
+func TestExtractDeathsPerspective(t *testing.T) {
+	parser := &fakeParser{
+		parsed: model.ParsedDemo{
+			Demo:     "match.dem",
+			TickRate: 64,
+			Kills: []model.KillEvent{
+				{Tick: 100, Round: 1, VictimID: "v1", KillerSlot: 7, IsWallbang: true},
+			},
+			Deaths: []model.KillEvent{
+				{Tick: 400, Round: 2, KillerID: "k1", VictimID: "steam", VictimSlot: 7},
+			},
+		},
+	}
+	eng := New(parser, service.NewHighlightService())
+
+	res, err := eng.Extract(context.Background(), ExtractOptions{
+		DemoPath:    "match.dem",
+		SteamID:     "steam",
+		Perspective: model.PerspectiveDeaths,
+	})
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(res.Highlights) != 1 || res.Highlights[0].Type != model.HighlightDeath {
+		t.Fatalf("expected only the death highlight, got %+v", res.Highlights)
+	}
+	if res.Highlights[0].PlayerSlot != 7 {
+		t.Fatalf("expected POV on the victim slot, got %d", res.Highlights[0].PlayerSlot)
+	}
+}
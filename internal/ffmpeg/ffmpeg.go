@@ -0,0 +1,239 @@
+// Package ffmpeg post-processes the recordings of a manifest with ffmpeg:
+// every segment is cut out of its take with the pre-roll trimmed, faded and
+// captioned, then the parts are joined in order with a chapter each. A job
+// renders as a shell script with its concat list and chapters, or runs
+// directly through a local ffmpeg.
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/captions"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+)
+
+// Builder turns manifests into jobs. Lead is how much of each part's
+// pre-roll is kept before its first kill (negative keeps all of it). Fade
+// is the fade in and out of every part, and CaptionSeconds how long its
+// caption shows (0 disables captions). FontFile is passed to drawtext when
+// set, for ffmpeg builds without fontconfig. Captions words the captions.
+// VideoFile is the file HLAE's FFmpeg presets write into each stream
+// folder. Encoder holds the output options of every part.
+type Builder struct {
+	Lead           float64
+	Fade           float64
+	CaptionSeconds float64
+	FontFile       string
+	Captions       captions.Captioner
+	VideoFile      string
+	Encoder        []string
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		Lead:           1,
+		Fade:           0.5,
+		CaptionSeconds: 3,
+		Captions:       *captions.NewCaptioner(),
+		VideoFile:      "video.mp4",
+		Encoder:        []string{"-c:v", "libx264", "-preset", "medium", "-crf", "18", "-pix_fmt", "yuv420p"},
+	}
+}
+
+// Job is the ffmpeg work for one manifest. Paths other than Base and the
+// media are relative to Dir, where the commands run.
+type Job struct {
+	Name     string
+	Dir      string
+	Base     string
+	PartsDir string
+	Concat   string
+	Chapters string
+	Output   string
+	Parts    []Part
+	Commands [][]string
+}
+
+// Part is one segment cut from a take: In to Out seconds of Media, placed
+// at Start seconds in the output.
+type Part struct {
+	Media   string
+	In      float64
+	Out     float64
+	Start   float64
+	Caption string
+	Path    string
+	Text    string
+}
+
+// Duration is the length of the part in the output.
+func (p Part) Duration() float64 {
+	return roundSeconds(p.Out - p.In)
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Build plans the job of the manifest, writing next to base (the manifest
+// path without .manifest.json). Only the first layer of each take is cut.
+func (b *Builder) Build(manifest hlae.Manifest, base string) (Job, error) {
+	name := filepath.Base(base)
+	safe := unsafeName.ReplaceAllString(name, "_")
+	job := Job{
+		Name:     name,
+		Dir:      filepath.Dir(base),
+		Base:     base,
+		PartsDir: safe + ".parts",
+		Concat:   name + ".ffconcat",
+		Chapters: name + ".ffmeta",
+		Output:   name + ".mp4",
+	}
+	fps := manifest.FrameRate
+	if fps <= 0 {
+		fps = 60
+	}
+
+	start := 0.0
+	for _, recording := range manifest.Recordings {
+		if len(recording.Streams) == 0 {
+			continue
+		}
+		media := recording.Streams[0] + "/" + b.VideoFile
+		for _, piece := range b.pieces(manifest, recording) {
+			part := Part{
+				Media:   media,
+				In:      piece.In,
+				Out:     piece.Out,
+				Caption: piece.Caption,
+				Path:    fmt.Sprintf("%s/part%03d.mp4", job.PartsDir, len(job.Parts)+1),
+			}
+			if b.Lead >= 0 && piece.FirstKill >= 0 {
+				part.In = roundSeconds(max(piece.In, piece.FirstKill-b.Lead))
+			}
+			if part.Duration() <= 0 {
+				continue
+			}
+			part.Start = roundSeconds(start)
+			if b.CaptionSeconds > 0 && part.Caption != "" {
+				part.Text = strings.TrimSuffix(part.Path, ".mp4") + ".txt"
+			}
+			job.Parts = append(job.Parts, part)
+			job.Commands = append(job.Commands, b.partCommand(part, fps))
+			start += part.Duration()
+		}
+	}
+	if len(job.Parts) == 0 {
+		return Job{}, fmt.Errorf("manifest of %s has no recordings to cut", manifest.Target)
+	}
+	job.Commands = append(job.Commands, []string{
+		"ffmpeg", "-hide_banner", "-y",
+		"-f", "concat", "-safe", "0", "-i", job.Concat,
+		"-i", job.Chapters,
+		"-map", "0", "-map_metadata", "1", "-map_chapters", "1",
+		"-c", "copy", job.Output,
+	})
+	return job, nil
+}
+
+// partCommand cuts, fades and captions one part. Seeking before the input
+// is frame-accurate since the part is re-encoded.
+func (b *Builder) partCommand(part Part, fps int) []string {
+	duration := part.Duration()
+	var filters []string
+	if fade := min(b.Fade, duration/2); fade > 0 {
+		filters = append(filters,
+			fmt.Sprintf("fade=t=in:st=0:d=%s", seconds(fade)),
+			fmt.Sprintf("fade=t=out:st=%s:d=%s", seconds(duration-fade), seconds(fade)))
+	}
+	if part.Text != "" {
+		drawtext := "drawtext=textfile=" + part.Text
+		if b.FontFile != "" {
+			drawtext += ":fontfile=" + filterPath(b.FontFile)
+		}
+		drawtext += fmt.Sprintf(":fontsize=h/18:fontcolor=white:borderw=3:x=(w-text_w)/2:y=h-text_h-h/12:enable='lt(t,%s)'", seconds(b.CaptionSeconds))
+		filters = append(filters, drawtext)
+	}
+
+	args := []string{"ffmpeg", "-hide_banner", "-y", "-ss", seconds(part.In), "-i", part.Media, "-t", seconds(duration)}
+	if len(filters) > 0 {
+		args = append(args, "-vf", strings.Join(filters, ","))
+	}
+	args = append(args, "-r", fmt.Sprint(fps), "-an")
+	args = append(args, b.Encoder...)
+	return append(args, part.Path)
+}
+
+// piece is a stretch of a take played without leaving its segment.
+// FirstKill is the first kill in it, -1 when it has none.
+type piece struct {
+	In        float64
+	Out       float64
+	FirstKill float64
+	Caption   string
+}
+
+// pieces splits a take at its segment boundaries: where playback jumps,
+// except for jumps between the kills of one highlight, or switches POV
+// for a replay. A clip take is usually one piece, a montage one per segment
+// and replay.
+func (b *Builder) pieces(manifest hlae.Manifest, recording hlae.ManifestRecording) []piece {
+	var out []piece
+	round := 0
+	for i, cut := range recording.Cuts {
+		if i == 0 || !recording.Continues(recording.Cuts[i-1], cut) {
+			out = append(out, piece{In: cut.At, FirstKill: -1})
+		}
+		p := &out[len(out)-1]
+		p.Out = roundSeconds(cut.At + cut.Duration)
+		if cut.ReplaySlot != 0 && p.Caption == "" {
+			p.Caption = replayCaption(round)
+		}
+		end := cut.At + cut.Duration
+		for _, h := range recording.Highlights {
+			for _, at := range h.KillTimes {
+				if at < cut.At || at > end {
+					continue
+				}
+				if p.FirstKill < 0 || at < p.FirstKill {
+					p.FirstKill = at
+				}
+				p.Caption = joinCaption(p.Caption, b.Captions.Text(manifest, recording, h))
+				round = h.Round
+				break
+			}
+		}
+	}
+	return out
+}
+
+func replayCaption(round int) string {
+	if round == 0 {
+		return "Replay"
+	}
+	return fmt.Sprintf("Round %d — replay", round)
+}
+
+const captionSeparator = " / "
+
+func joinCaption(caption, next string) string {
+	switch {
+	case caption == "":
+		return next
+	case slices.Contains(strings.Split(caption, captionSeparator), next):
+		return caption
+	}
+	return caption + captionSeparator + next
+}
+
+func roundSeconds(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// seconds formats a time for ffmpeg, to the millisecond.
+func seconds(value float64) string {
+	return fmt.Sprint(roundSeconds(value))
+}
@@ -0,0 +1,170 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+func montageManifest(t *testing.T) hlae.Manifest {
+	t.Helper()
+	result := model.HighlightResult{
+		Demo:     "mirage.dem",
+		SteamID:  "76561198000000001",
+		TickRate: 64,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightHeadshot, Round: 3, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000, TickEnd: 1000},
+			{Type: model.HighlightWallbang, Round: 9, PlayerSlot: 7, SegmentFrom: 5000, SegmentTo: 5000, TickStart: 5000, TickEnd: 5000},
+		},
+	}
+	target := hlae.Target{Mode: hlae.ModeMontage, Path: "reel.cfg", Name: "reel"}
+	files, err := hlae.BuildTarget(result, hlae.Options{OutputPath: "/rec", PreRollSeconds: 3, PostRollSeconds: 2}, target)
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	var manifest hlae.Manifest
+	if err := json.Unmarshal([]byte(files[len(files)-1].Content), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	return manifest
+}
+
+func TestBuildCutsMontageAtSegments(t *testing.T) {
+	job, err := NewBuilder().Build(montageManifest(t), "out/reel")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if len(job.Parts) != 2 {
+		t.Fatalf("expected a part per segment, got %+v", job.Parts)
+	}
+	// Segments play 5s each, the kill 3s in; a 1s lead trims 2s of each.
+	want := []Part{
+		{In: 2, Out: 5, Start: 0, Caption: "Round 3 — headshot", Path: "reel.parts/part001.mp4", Text: "reel.parts/part001.txt"},
+		{In: 7, Out: 10, Start: 3, Caption: "Round 9 — wallbang", Path: "reel.parts/part002.mp4", Text: "reel.parts/part002.txt"},
+	}
+	for i, part := range job.Parts {
+		if !strings.HasSuffix(part.Media, "/take0000/screen/video.mp4") {
+			t.Fatalf("expected the part to cut the screen stream, got %s", part.Media)
+		}
+		part.Media = ""
+		if !reflect.DeepEqual(part, want[i]) {
+			t.Fatalf("part %d: expected %+v, got %+v", i+1, want[i], part)
+		}
+	}
+
+	first := strings.Join(job.Commands[0], " ")
+	for _, arg := range []string{"-ss 2 -i ", " -t 3 ", "fade=t=in:st=0:d=0.5,fade=t=out:st=2.5:d=0.5,drawtext=textfile=reel.parts/part001.txt:", "enable='lt(t,3)'", "-r 60 -an -c:v libx264"} {
+		if !strings.Contains(first, arg) {
+			t.Fatalf("expected %q in %s", arg, first)
+		}
+	}
+	if last := job.Commands[len(job.Commands)-1]; last[len(last)-1] != "reel.mp4" || !strings.Contains(strings.Join(last, " "), "-f concat -safe 0 -i reel.ffconcat -i reel.ffmeta") {
+		t.Fatalf("expected a final concat, got %v", last)
+	}
+
+	if got := job.ConcatList(); got != "ffconcat version 1.0\nfile 'reel.parts/part001.mp4'\nfile 'reel.parts/part002.mp4'\n" {
+		t.Fatalf("unexpected concat list:\n%s", got)
+	}
+	if got := job.Metadata(); !strings.Contains(got, "START=3000\nEND=6000\ntitle=Round 9 — wallbang\n") {
+		t.Fatalf("expected chapters on the joined timeline:\n%s", got)
+	}
+
+	paths := make([]string, 0)
+	for _, file := range job.Files() {
+		paths = append(paths, filepath.ToSlash(file.Path))
+	}
+	wantPaths := []string{"out/reel.ffmpeg.sh", "out/reel.ffconcat", "out/reel.ffmeta", "out/reel.parts/part001.txt", "out/reel.parts/part002.txt"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("expected files %v, got %v", wantPaths, paths)
+	}
+	script := job.Script()
+	if !strings.Contains(script, "cd \"$(dirname \"$0\")\"\nmkdir -p reel.parts\nffmpeg -hide_banner -y -ss 2 -i /rec/") {
+		t.Fatalf("unexpected script:\n%s", script)
+	}
+
+	keep := NewBuilder()
+	keep.Lead, keep.CaptionSeconds, keep.Fade = -1, 0, 0
+	job, err = keep.Build(montageManifest(t), "reel")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if job.Parts[1].In != 5 || job.Parts[1].Start != 5 || job.Parts[0].Text != "" || strings.Contains(strings.Join(job.Commands[0], " "), "-vf") {
+		t.Fatalf("expected whole segments without filters, got %+v", job.Parts)
+	}
+}
+
+func TestMillisecondsRoundToTheNearest(t *testing.T) {
+	for value, want := range map[float64]int{1.005: 1005, 2.0004: 2000, 0.9996: 1000, 12.25: 12250} {
+		if got := milliseconds(value); got != want {
+			t.Fatalf("milliseconds(%v): expected %d, got %d", value, want, got)
+		}
+	}
+}
+
+func TestPiecesKeepKillGapJumpsAndSplitReplays(t *testing.T) {
+	recording := hlae.ManifestRecording{
+		Cuts: []hlae.ManifestCut{
+			{TickStart: 800, TickEnd: 1128, At: 0, Duration: 5.125, Speed: 1},
+			{TickStart: 2800, TickEnd: 3128, At: 5.125, Duration: 5.125, Speed: 1},
+			{TickStart: 936, TickEnd: 1064, At: 10.25, Duration: 2, Speed: 1, ReplaySlot: 4},
+		},
+		Highlights: []hlae.ManifestHighlight{
+			{Type: model.HighlightMultiKill, Round: 5, TickStart: 1000, TickEnd: 3000, Kills: 2, KillTimes: []float64{3.125, 8.25}},
+		},
+	}
+	got := NewBuilder().pieces(hlae.Manifest{}, recording)
+	want := []piece{
+		{In: 0, Out: 10.25, FirstKill: 3.125, Caption: "Round 5 — 2K"},
+		{In: 10.25, Out: 12.25, FirstKill: -1, Caption: "Round 5 — replay"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestRunnerRunsJobCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	fake := filepath.Join(dir, "fake-ffmpeg")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\necho \"$@\" >> '"+log+"'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := NewBuilder().Build(montageManifest(t), filepath.Join(dir, "reel"))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	runner := NewRunner()
+	runner.Binary = fake
+	runner.Stdout, runner.Stderr = nil, nil
+	if err := runner.Run(context.Background(), job); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	calls, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(calls)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[2], "-c copy reel.mp4") {
+		t.Fatalf("expected two cuts and a join, got:\n%s", calls)
+	}
+	if caption, err := os.ReadFile(filepath.Join(dir, "reel.parts", "part002.txt")); err != nil || string(caption) != "Round 9 — wallbang" {
+		t.Fatalf("expected the caption file, got %q, %v", caption, err)
+	}
+
+	runner.Binary = filepath.Join(dir, "missing-ffmpeg")
+	if err := runner.Run(context.Background(), job); err == nil || !strings.Contains(err.Error(), "ffmpeg not found") {
+		t.Fatalf("expected a missing ffmpeg error, got %v", err)
+	}
+}
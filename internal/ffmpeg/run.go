@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/atomicfile"
+)
+
+// Runner runs jobs through a local ffmpeg. Binary is looked up on PATH
+// unless it is a path; ffmpeg's own output goes to Stdout and Stderr, and
+// Logf reports progress when set.
+type Runner struct {
+	Binary string
+	Stdout io.Writer
+	Stderr io.Writer
+	Logf   func(format string, args ...any)
+}
+
+func NewRunner() *Runner {
+	return &Runner{Binary: "ffmpeg", Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// Run writes the job's files and runs its commands in order from the job's
+// folder, stopping at the first that fails.
+func (r *Runner) Run(ctx context.Context, job Job) error {
+	binary, err := exec.LookPath(r.Binary)
+	if err != nil {
+		return fmt.Errorf("ffmpeg not found (install it or put it on PATH): %w", err)
+	}
+	if err := os.MkdirAll(job.path(job.PartsDir), 0o755); err != nil {
+		return err
+	}
+	for _, file := range job.Files() {
+		if err := atomicfile.WriteFile(file.Path, []byte(file.Content), 0o644); err != nil {
+			return err
+		}
+	}
+	for i, command := range job.Commands {
+		r.logf("[%d/%d] %s", i+1, len(job.Commands), command[len(command)-1])
+		cmd := exec.CommandContext(ctx, binary, command[1:]...)
+		cmd.Dir = job.Dir
+		cmd.Stdout, cmd.Stderr = r.Stdout, r.Stderr
+		if err := cmd.Run(); err != nil {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return fmt.Errorf("ffmpeg for %s: %w", command[len(command)-1], err)
+		}
+	}
+	r.logf("saved %s", filepath.Join(job.Dir, job.Output))
+	return nil
+}
+
+func (r *Runner) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+	}
+}
@@ -0,0 +1,122 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+)
+
+// ScriptPath is where the shell script of the job with base goes.
+func ScriptPath(base string) string {
+	return base + ".ffmpeg.sh"
+}
+
+// Files are what the job needs on disk next to its base: the shell script,
+// the concat list, the chapters and the caption text of every part.
+func (j Job) Files() []hlae.File {
+	files := []hlae.File{
+		{Path: ScriptPath(j.Base), Content: j.Script()},
+		{Path: j.path(j.Concat), Content: j.ConcatList()},
+		{Path: j.path(j.Chapters), Content: j.Metadata()},
+	}
+	for _, part := range j.Parts {
+		if part.Text != "" {
+			files = append(files, hlae.File{Path: j.path(part.Text), Content: part.Caption})
+		}
+	}
+	return files
+}
+
+func (j Job) path(name string) string {
+	return filepath.Join(j.Dir, filepath.FromSlash(name))
+}
+
+// Script renders the job as a POSIX shell script that runs from its own
+// folder.
+func (j Job) Script() string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&sb, "# Cuts, captions and joins the recordings of %s into %s.\n", j.Name, j.Output)
+	sb.WriteString("set -e\n")
+	sb.WriteString("cd \"$(dirname \"$0\")\"\n")
+	fmt.Fprintf(&sb, "mkdir -p %s\n", shellQuote(j.PartsDir))
+	for _, command := range j.Commands {
+		quoted := make([]string, len(command))
+		for i, arg := range command {
+			quoted[i] = shellQuote(arg)
+		}
+		sb.WriteString(strings.Join(quoted, " ") + "\n")
+	}
+	return sb.String()
+}
+
+// ConcatList renders the ffconcat list of the parts, in order.
+func (j Job) ConcatList() string {
+	var sb strings.Builder
+	sb.WriteString("ffconcat version 1.0\n")
+	for _, part := range j.Parts {
+		fmt.Fprintf(&sb, "file %s\n", concatQuote(part.Path))
+	}
+	return sb.String()
+}
+
+// Metadata renders an ffmetadata file with a chapter per part, in
+// milliseconds of the joined output.
+func (j Job) Metadata() string {
+	var sb strings.Builder
+	sb.WriteString(";FFMETADATA1\n")
+	fmt.Fprintf(&sb, "title=%s\n", metadataEscape(j.Name))
+	for i, part := range j.Parts {
+		title := part.Caption
+		if title == "" {
+			title = fmt.Sprintf("Part %d", i+1)
+		}
+		sb.WriteString("\n[CHAPTER]\nTIMEBASE=1/1000\n")
+		fmt.Fprintf(&sb, "START=%d\n", milliseconds(part.Start))
+		fmt.Fprintf(&sb, "END=%d\n", milliseconds(part.Start+part.Duration()))
+		fmt.Fprintf(&sb, "title=%s\n", metadataEscape(title))
+	}
+	return sb.String()
+}
+
+// milliseconds rounds a time to the nearest millisecond.
+func milliseconds(value float64) int {
+	return int(math.Round(value * 1000))
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:=,+-]+$`)
+
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func concatQuote(path string) string {
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// metadataEscape escapes the characters ffmetadata gives a meaning.
+func metadataEscape(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(`=;#\`+"\n", r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// filterPath quotes a file path for a filter option: forward slashes, and
+// the drive colon escaped from the option parser.
+func filterPath(path string) string {
+	path = strings.ReplaceAll(path, `\`, "/")
+	path = strings.ReplaceAll(path, ":", `\:`)
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
@@ -0,0 +1,204 @@
+package hlae
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// IntroStyle selects the free-camera path flown before a segment.
+type IntroStyle string
+
+const (
+	// IntroOrbit circles the killer's kill position and lands on their eyes.
+	IntroOrbit IntroStyle = "orbit"
+	// IntroSpawn flies from the killer's freeze-time position to the kill.
+	IntroSpawn IntroStyle = "spawn"
+)
+
+func AllIntroStyles() []IntroStyle {
+	return []IntroStyle{IntroOrbit, IntroSpawn}
+}
+
+const (
+	eyeHeight     = 64.0
+	orbitRadius   = 180.0
+	orbitHeight   = 90.0
+	orbitSweep    = 270.0
+	orbitKeys     = 8
+	spawnLift     = 200.0
+	spawnKeys     = 6
+	approachBack  = 80.0
+	approachLift  = 20.0
+	campathFOV    = 90.0
+	campathFormat = "%.2f"
+)
+
+// campathIntro is the free-camera part at the start of a segment, before the
+// POV locks onto the player.
+type campathIntro struct {
+	StartTick int
+	EndTick   int
+	Keys      []campathKey
+}
+
+// campathKey is one mirv_campath keyframe: a demo tick, a camera position
+// and Source view angles in degrees.
+type campathKey struct {
+	Tick             float64
+	Position         model.Vector
+	Pitch, Yaw, Roll float64
+}
+
+func (b *ScriptBuilder) introEnabled(h model.Highlight) bool {
+	return b.IntroTicks > 0 && h.Scene != nil && len(b.IntroTypes) > 0 && b.IntroTypes[h.Type]
+}
+
+// introFor plans the intro of a segment opened by h at startTick.
+func (b *ScriptBuilder) introFor(h model.Highlight, startTick int) *campathIntro {
+	if !b.introEnabled(h) {
+		return nil
+	}
+	intro := &campathIntro{StartTick: startTick, EndTick: startTick + b.IntroTicks}
+	scene := *h.Scene
+	eye := addZ(scene.Killer, eyeHeight)
+	target := addZ(scene.Victim, eyeHeight)
+
+	var positions []model.Vector
+	var looks []model.Vector
+	if b.IntroStyle == IntroSpawn && scene.KillerSpawn != nil {
+		from := addZ(*scene.KillerSpawn, eyeHeight+spawnLift)
+		back := approachPoint(eye, target)
+		for i := range spawnKeys {
+			f := float64(i) / spawnKeys
+			positions = append(positions, lerp(from, back, f))
+			looks = append(looks, target)
+		}
+	} else {
+		start := headingDegrees(eye, target) + 180
+		for i := range orbitKeys {
+			angle := (start + orbitSweep*float64(i)/orbitKeys) * math.Pi / 180
+			positions = append(positions, model.Vector{
+				X: eye.X + orbitRadius*math.Cos(angle),
+				Y: eye.Y + orbitRadius*math.Sin(angle),
+				Z: eye.Z + orbitHeight,
+			})
+			looks = append(looks, eye)
+		}
+	}
+	// The last key sits on the killer's eyes looking at the victim, so the
+	// cut to the locked POV is seamless.
+	positions = append(positions, eye)
+	looks = append(looks, target)
+
+	last := len(positions) - 1
+	for i, position := range positions {
+		tick := float64(intro.StartTick) + float64(b.IntroTicks)*float64(i)/float64(last)
+		pitch, yaw := lookAngles(position, looks[i])
+		intro.Keys = append(intro.Keys, campathKey{Tick: tick, Position: position, Pitch: pitch, Yaw: yaw})
+	}
+	return intro
+}
+
+// campathCommands turns the segment's intro on at its start and hands the
+// view back to the player's POV at its end. The first intro of the script
+// also shifts the loaded path onto the game clock.
+func (b *ScriptBuilder) campathCommands(seg recordingSegment, first bool) []tickCommand {
+	if seg.Intro == nil {
+		return nil
+	}
+	on := []string{"mirv_campath enabled 1"}
+	if first {
+		on = append([]string{"mirv_campath edit start"}, on...)
+	}
+	off := append([]string{"mirv_campath enabled 0"}, povCommandsBySlot(seg.PlayerSlot)...)
+	return []tickCommand{
+		{Tick: seg.Intro.StartTick, Body: joinCommands(on...)},
+		{Tick: seg.Intro.EndTick, Body: joinCommands(off...)},
+	}
+}
+
+// BuildCampath renders every intro of the selected highlights as one
+// mirv_campath XML file, loaded once by the script. Key times are demo
+// seconds derived from ticks; the script aligns them to the game clock when
+// the first intro starts. Angles use HLAE's axis names: rx roll, ry pitch,
+// rz yaw. It returns "" when no segment has an intro.
+func (b *ScriptBuilder) BuildCampath(result model.HighlightResult, types model.Selection) string {
+	if result.TickRate <= 0 {
+		return ""
+	}
+	var keys []campathKey
+	for _, seg := range b.resolveSegments(result.Highlights, types) {
+		if seg.Intro != nil {
+			keys = append(keys, seg.Intro.Keys...)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+
+	var w strings.Builder
+	w.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<campath>\n\t<points>\n")
+	for _, key := range keys {
+		fmt.Fprintf(&w, "\t\t<p t=\"%s\" x=\"%s\" y=\"%s\" z=\"%s\" fov=\"%s\" rx=\"%s\" ry=\"%s\" rz=\"%s\"/>\n",
+			formatCoord(key.Tick/result.TickRate), formatCoord(key.Position.X), formatCoord(key.Position.Y), formatCoord(key.Position.Z),
+			formatCoord(campathFOV), formatCoord(key.Roll), formatCoord(key.Pitch), formatCoord(key.Yaw))
+	}
+	w.WriteString("\t</points>\n</campath>\n")
+	return w.String()
+}
+
+func hasIntro(segs []recordingSegment) bool {
+	return firstIntroIndex(segs) >= 0
+}
+
+// firstIntroIndex is the index of the first segment with an intro, or -1.
+func firstIntroIndex(segs []recordingSegment) int {
+	for i, seg := range segs {
+		if seg.Intro != nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// approachPoint is just behind and above the killer, facing the victim.
+func approachPoint(eye model.Vector, target model.Vector) model.Vector {
+	heading := headingDegrees(eye, target) * math.Pi / 180
+	return model.Vector{
+		X: eye.X - approachBack*math.Cos(heading),
+		Y: eye.Y - approachBack*math.Sin(heading),
+		Z: eye.Z + approachLift,
+	}
+}
+
+// lookAngles returns the Source pitch (positive looks down) and yaw that
+// point a camera at from towards to.
+func lookAngles(from model.Vector, to model.Vector) (float64, float64) {
+	dx, dy, dz := to.X-from.X, to.Y-from.Y, to.Z-from.Z
+	pitch := math.Atan2(-dz, math.Hypot(dx, dy)) * 180 / math.Pi
+	return pitch, math.Atan2(dy, dx) * 180 / math.Pi
+}
+
+func headingDegrees(from model.Vector, to model.Vector) float64 {
+	return math.Atan2(to.Y-from.Y, to.X-from.X) * 180 / math.Pi
+}
+
+func lerp(a model.Vector, b model.Vector, f float64) model.Vector {
+	return model.Vector{X: a.X + (b.X-a.X)*f, Y: a.Y + (b.Y-a.Y)*f, Z: a.Z + (b.Z-a.Z)*f}
+}
+
+func addZ(v model.Vector, dz float64) model.Vector {
+	return model.Vector{X: v.X, Y: v.Y, Z: v.Z + dz}
+}
+
+// formatCoord prints value with two decimals, without a "-0.00".
+func formatCoord(value float64) string {
+	rounded := math.Round(value*100) / 100
+	if rounded == 0 {
+		rounded = 0
+	}
+	return fmt.Sprintf(campathFormat, rounded)
+}
@@ -0,0 +1,116 @@
+package hlae
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// chainExecDelay is the demo tick at which a chained demo's script is exec'd,
+// once playdemo has loaded the demo.
+const chainExecDelay = 2
+
+// BuildChain renders target over the results of several demos, in order: one
+// script per demo with highlights in the target, and a master script at
+// target.Path that plays the demos in turn. Each demo's script is exec'd
+// from a folder named after the master, and loads the next demo once its
+// last segment is recorded. Takes are named <target>_<NN>_<demo> so they
+// sort in chain order, and one manifest covers the whole chain.
+//
+// Demos are loaded with playdemo by file name, so they must sit in the
+// game's csgo folder; the master and its folder go to csgo/cfg.
+func BuildChain(results []model.HighlightResult, options Options, target Target) ([]File, error) {
+	folder := strings.TrimSuffix(target.Path, filepath.Ext(target.Path))
+	execDir := filepath.Base(folder)
+	if !unquotedSafe(execDir) {
+		return nil, fmt.Errorf("chain %q: script name must not contain spaces, ';' or quotes", target.Path)
+	}
+
+	type chainPart struct {
+		Result model.HighlightResult
+		Demo   string
+	}
+	var parts []chainPart
+	for _, result := range results {
+		demo := strings.TrimSuffix(filepath.Base(result.Demo), filepath.Ext(result.Demo))
+		if !unquotedSafe(demo) {
+			return nil, fmt.Errorf("chain %q: demo name %q must not contain spaces, ';' or quotes", target.Path, demo)
+		}
+		if len(configuredBuilder(result, target.options(options)).resolveSegments(result.Highlights, target.Types)) == 0 {
+			continue
+		}
+		parts = append(parts, chainPart{Result: result, Demo: demo})
+	}
+
+	manifest := Manifest{
+		Target:     target.Name,
+		Mode:       target.Mode.String(),
+		Recordings: make([]ManifestRecording, 0),
+	}
+	date := time.Now()
+	var files []File
+	var master strings.Builder
+	writeCommandLine(&master, "mirv_cmd clear")
+	for k, part := range parts {
+		partName := fmt.Sprintf("%02d_%s", k+1, sanitizeNameToken(part.Demo))
+		next := "disconnect"
+		if k+1 < len(parts) {
+			next = chainAlias(k + 2)
+		}
+		partTarget := target
+		partTarget.Path = filepath.Join(folder, partName+".cfg")
+		partTarget.Name = target.Name + "_" + partName
+
+		partFiles, partManifest, err := renderTarget(part.Result, options, partTarget, renderContext{
+			ExecName:  execDir + "/" + partName,
+			Next:      next,
+			FirstTake: len(manifest.Recordings),
+			Date:      date,
+		})
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, partFiles...)
+		if k == 0 {
+			manifest.SteamID = partManifest.SteamID
+			manifest.TickRate = partManifest.TickRate
+			manifest.FrameRate = partManifest.FrameRate
+			manifest.PreRollSeconds = partManifest.PreRollSeconds
+			manifest.Layers = partManifest.Layers
+		}
+		for _, recording := range partManifest.Recordings {
+			recording.Demo = part.Result.Demo
+			manifest.Recordings = append(manifest.Recordings, recording)
+		}
+
+		alias := chainAlias(k + 1)
+		writeCommandLine(&master, fmt.Sprintf(`alias %s_exec "exec %s/%s"`, alias, execDir, partName))
+		writeCommandLine(&master, fmt.Sprintf(`alias %s "mirv_cmd clear; mirv_cmd addAtTick %d %s_exec; playdemo %s"`, alias, chainExecDelay, alias, part.Demo))
+	}
+
+	if len(parts) == 0 {
+		writeCommandLine(&master, `echo "No highlights found in any demo."`)
+	} else {
+		demos := make([]string, 0, len(parts))
+		for _, part := range parts {
+			demos = append(demos, part.Demo)
+		}
+		writeCommandLine(&master, fmt.Sprintf(`echo "Chaining %d demos: %s"`, len(parts), strings.Join(demos, ", ")))
+		writeCommandLine(&master, "toggleconsole")
+		writeCommandLine(&master, chainAlias(1))
+	}
+
+	file, err := manifestFile(target.Path, manifest)
+	if err != nil {
+		return nil, err
+	}
+	files = append([]File{{Path: target.Path, Content: master.String()}}, files...)
+	return append(files, file), nil
+}
+
+func chainAlias(part int) string {
+	return fmt.Sprintf("hlc%d", part)
+}
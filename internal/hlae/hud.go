@@ -0,0 +1,118 @@
+package hlae
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// HUDPreset selects what the game draws over a recording.
+type HUDPreset string
+
+const (
+	// HUDKillfeed hides the HUD but the death notices, filtered to the
+	// player's own entries.
+	HUDKillfeed HUDPreset = "killfeed"
+	// HUDFull keeps the whole game HUD, radar and every death notice.
+	HUDFull HUDPreset = "full"
+	// HUDClean hides the HUD and the death notices: the world alone.
+	HUDClean HUDPreset = "clean"
+	// HUDHighlight hides the HUD but the death notices, and keeps every
+	// notice with the player's own highlighted.
+	HUDHighlight HUDPreset = "highlight"
+)
+
+func AllHUDPresets() []HUDPreset {
+	return []HUDPreset{HUDKillfeed, HUDFull, HUDClean, HUDHighlight}
+}
+
+// HUD is a recording's HUD preset and its mirv_deathmsg tweaks. Lifetime
+// keeps death notices on screen this many seconds (0 = game default). Color
+// tints the player's name in their notices, as hex RRGGBB (empty = team
+// colour). HideVictims blanks the victim names of the notices shown.
+type HUD struct {
+	Preset      HUDPreset
+	Lifetime    float64
+	Color       string
+	HideVictims bool
+}
+
+func (h HUD) preset() HUDPreset {
+	if h.Preset == "" {
+		return HUDKillfeed
+	}
+	return h.Preset
+}
+
+func (h HUD) deathNotices() bool {
+	return h.preset() != HUDClean
+}
+
+// hudCommands are the setup lines that draw the preset's HUD.
+func (b *ScriptBuilder) hudCommands() []string {
+	drawHud, radar, notices := 0, -1, 1
+	switch b.HUD.preset() {
+	case HUDFull:
+		drawHud, radar, notices = 1, 0, 0
+	case HUDClean:
+		notices = -1
+	}
+	return []string{
+		fmt.Sprintf("cl_drawhud %d", drawHud),
+		"r_show_build_info false",
+		fmt.Sprintf("cl_drawhud_force_radar %d", radar),
+		fmt.Sprintf("cl_drawhud_force_deathnotices %d", notices),
+		"mirv_deathmsg filter clear",
+	}
+}
+
+// deathmsgCommands are the setup lines that filter and style the death
+// notices for the player. Rules run in order: the killfeed preset blocks
+// other players' notices first, so the styling rules only see the
+// player's own.
+func (b *ScriptBuilder) deathmsgCommands(result model.HighlightResult) []string {
+	hud := b.HUD
+	if !hud.deathNotices() {
+		return nil
+	}
+	var lines []string
+	if hud.Lifetime > 0 {
+		lines = append(lines, fmt.Sprintf("mirv_deathmsg lifetime %s", strconv.FormatFloat(hud.Lifetime, 'f', -1, 64)))
+	}
+	steamID := result.SteamID
+	if steamID == "" {
+		if hud.HideVictims {
+			lines = append(lines, `mirv_deathmsg filter add "victimName= "`)
+		}
+		return lines
+	}
+
+	match := deathmsgMatchKey(result.Perspective)
+	lines = append(lines, fmt.Sprintf("mirv_deathmsg localPlayer x%s", steamID))
+	if hud.preset() == HUDKillfeed {
+		lines = append(lines, fmt.Sprintf("mirv_deathmsg filter add %s=!x%s block=1 lastRule=1", match, steamID))
+	}
+	if hud.Color != "" {
+		lines = append(lines, fmt.Sprintf("mirv_deathmsg filter add %s=x%s %sColor=%s", match, steamID, deathmsgRole(result.Perspective), hud.Color))
+	}
+	if hud.HideVictims {
+		lines = append(lines, `mirv_deathmsg filter add "victimName= "`)
+	}
+	return lines
+}
+
+// deathmsgMatchKey keeps the killfeed to the player's own entries: their kills,
+// or in a deaths reel the notices where they are the victim.
+func deathmsgMatchKey(perspective model.Perspective) string {
+	return deathmsgRole(perspective) + "Match"
+}
+
+// deathmsgRole is the player's side of their notices: the attacker of
+// their kills, or the victim in a deaths reel.
+func deathmsgRole(perspective model.Perspective) string {
+	if perspective == model.PerspectiveDeaths {
+		return "victim"
+	}
+	return "attacker"
+}
@@ -0,0 +1,100 @@
+package hlae
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// LayerKind selects what a stream layer draws.
+type LayerKind string
+
+const (
+	// LayerScreen is the regular game view, the only stream without layers.
+	LayerScreen LayerKind = "screen"
+	// LayerWorld is the clean world without any HUD.
+	LayerWorld LayerKind = "world"
+	// LayerDeathmsg is the HUD alone (the filtered death notices) over a
+	// black matte, to key over the world layer.
+	LayerDeathmsg LayerKind = "deathmsg"
+	// LayerDepth is the depth buffer, for depth of field in post.
+	LayerDepth LayerKind = "depth"
+)
+
+func AllLayerKinds() []LayerKind {
+	return []LayerKind{LayerScreen, LayerWorld, LayerDeathmsg, LayerDepth}
+}
+
+// StreamLayer is one stream recorded in the same playthrough. Name is the
+// stream (and output folder) name; Preset overrides the target's FFmpeg
+// preset for this stream.
+type StreamLayer struct {
+	Name   string
+	Kind   LayerKind
+	Preset string
+}
+
+// layerCommands are the setup lines that replace the single screen stream
+// with the declared layers. Streams are removed before they are added so a
+// script can be pasted again in the same session.
+func (b *ScriptBuilder) layerCommands() []string {
+	screen := len(b.Layers) == 0
+	var lines []string
+	for _, layer := range b.Layers {
+		if layer.Kind == LayerScreen {
+			screen = true
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("mirv_streams remove %s", layer.Name))
+		switch layer.Kind {
+		case LayerDepth:
+			lines = append(lines, fmt.Sprintf("mirv_streams add depth %s", layer.Name))
+		case LayerDeathmsg:
+			lines = append(lines,
+				fmt.Sprintf("mirv_streams add baseFx %s", layer.Name),
+				fmt.Sprintf("mirv_streams edit %s drawHud 1", layer.Name),
+				fmt.Sprintf("mirv_streams edit %s clearBeforeHud black", layer.Name),
+			)
+		default:
+			lines = append(lines,
+				fmt.Sprintf("mirv_streams add normal %s", layer.Name),
+				fmt.Sprintf("mirv_streams edit %s drawHud 0", layer.Name),
+			)
+		}
+		lines = append(lines, fmt.Sprintf("mirv_streams edit %s settings %s", layer.Name, b.layerPreset(layer)))
+	}
+	lines = append(lines, fmt.Sprintf("mirv_streams record screen enabled %d", boolInt(screen)))
+	return lines
+}
+
+// screenPreset is the default stream settings: the screen layer's preset
+// when it declares one.
+func (b *ScriptBuilder) screenPreset() string {
+	for _, layer := range b.Layers {
+		if layer.Kind == LayerScreen {
+			return b.layerPreset(layer)
+		}
+	}
+	return b.ffmpegPreset()
+}
+
+// layerPreset is the layer's own preset, sanitized like the shared one it
+// falls back to, since both are written into console commands.
+func (b *ScriptBuilder) layerPreset(layer StreamLayer) string {
+	return cmp.Or(sanitizePresetToken(layer.Preset), b.ffmpegPreset())
+}
+
+// streamLayers is what a take records: the declared layers, or the screen
+// stream alone.
+func (b *ScriptBuilder) streamLayers() []StreamLayer {
+	if len(b.Layers) > 0 {
+		return b.Layers
+	}
+	return []StreamLayer{{Name: string(LayerScreen), Kind: LayerScreen}}
+}
+
+func boolInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,427 @@
+package hlae
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LintSeverity ranks a lint finding. Only errors make a script fail lint.
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+	LintNote    LintSeverity = "note"
+)
+
+const (
+	// lintMaxSteps bounds the simulated tick executions, so a script that
+	// seeks back forever is reported instead of hanging the linter.
+	lintMaxSteps = 100000
+	// lintMaxAliasDepth bounds alias expansion, like the game's own limit.
+	lintMaxAliasDepth = 32
+	// lintMaxSeekRepeats is how often the same seek may fire before playback
+	// counts as looping; generated scripts fire each seek once.
+	lintMaxSeekRepeats = 8
+)
+
+// LintOptions configures Lint. LastTick is the demo's last tick; 0 skips the
+// checks that need it. Exec resolves a script exec'd by name to its content,
+// so playback continues into split part scripts; without it, or for a name
+// it does not resolve, playback stops at the exec.
+type LintOptions struct {
+	LastTick int
+	Exec     func(name string) (string, bool)
+}
+
+// LintIssue is one finding. Script is the exec name of the part script the
+// line is in, empty for the linted script itself. Line is the 1-based script
+// line that scheduled or ran the command, Tick the demo tick it ran at (-1
+// before playback).
+type LintIssue struct {
+	Script   string
+	Line     int
+	Tick     int
+	Severity LintSeverity
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	line := strconv.Itoa(i.Line)
+	if i.Script != "" {
+		line = i.Script + ":" + line
+	}
+	if i.Tick < 0 {
+		return fmt.Sprintf("%s: %s: %s", line, i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s (tick %d)", line, i.Severity, i.Message, i.Tick)
+}
+
+// SimulatedTake is one recording of a simulated playback: the tick ranges it
+// captured, in order.
+type SimulatedTake struct {
+	Spans [][2]int
+}
+
+// LintReport is the outcome of a simulated playback.
+type LintReport struct {
+	Issues []LintIssue
+	Takes  []SimulatedTake
+}
+
+// Errors counts the error-level issues.
+func (r LintReport) Errors() int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == LintError {
+			count++
+		}
+	}
+	return count
+}
+
+// Lint plays a generated script back abstractly: it runs the setup, then
+// walks the demo ticks firing mirv_cmd entries in the order they were added,
+// following aliases, seeks and recordings, until the script disconnects,
+// hands over to another demo, or has nothing left to run.
+//
+// It reports unbalanced or nested quotes, a record start while recording, a
+// recording left open, seeks back into ticks the running take already
+// recorded, and commands or recordings past options.LastTick.
+func Lint(script string, options LintOptions) LintReport {
+	sim := &simulator{
+		options: options,
+		aliases: make(map[string]string),
+		seeks:   make(map[[2]int]int),
+		seen:    make(map[LintIssue]bool),
+		tick:    -1,
+		seek:    -1,
+	}
+	for i, line := range strings.Split(script, "\n") {
+		sim.line = i + 1
+		if !sim.checkQuotes(line) {
+			continue
+		}
+		for _, command := range splitCommands(line) {
+			sim.run(command, 0)
+		}
+		if sim.stopped {
+			break
+		}
+		if sim.seek >= 0 {
+			sim.pos, sim.seek = sim.seek, -1
+		}
+	}
+	sim.play()
+	return sim.report
+}
+
+// scheduledCommand is a mirv_cmd addAtTick entry.
+type scheduledCommand struct {
+	Tick   int
+	Body   string
+	Script string
+	Line   int
+}
+
+type simulator struct {
+	options  LintOptions
+	report   LintReport
+	aliases  map[string]string
+	schedule []scheduledCommand
+	// seeks counts how often each (from, to) seek fired.
+	seeks map[[2]int]int
+	seen  map[LintIssue]bool
+
+	script  string
+	line    int
+	tick    int
+	pos     int
+	seek    int
+	stopped bool
+
+	recording bool
+	take      SimulatedTake
+	spanStart int
+	// recorded is the last tick the running take captured before a seek.
+	recorded int
+}
+
+func (s *simulator) play() {
+	for steps := 0; !s.stopped; steps++ {
+		if steps >= lintMaxSteps {
+			s.issue(LintError, "playback does not terminate; a seek keeps re-firing itself")
+			break
+		}
+		next, ok := s.nextTick(s.pos)
+		if !ok {
+			break
+		}
+		s.tick = next
+		for _, cmd := range s.entriesAt(next) {
+			s.script, s.line = cmd.Script, cmd.Line
+			for _, command := range splitCommands(cmd.Body) {
+				s.run(command, 0)
+			}
+			if s.stopped {
+				break
+			}
+		}
+		if s.seek >= 0 {
+			key := [2]int{next, s.seek}
+			s.seeks[key]++
+			if s.seeks[key] > lintMaxSeekRepeats {
+				s.issue(LintError, fmt.Sprintf("playback loops: the seek to tick %d keeps firing", s.seek))
+				break
+			}
+			s.seekTo(s.seek)
+			s.seek = -1
+			continue
+		}
+		s.pos = next + 1
+	}
+	if s.recording {
+		s.issue(LintError, "record start without a matching record end")
+		s.closeTake(s.tick)
+	}
+	if s.options.LastTick > 0 {
+		for _, cmd := range s.schedule {
+			if cmd.Tick > s.options.LastTick {
+				s.script, s.line = cmd.Script, cmd.Line
+				s.tick = cmd.Tick
+				s.issue(LintError, fmt.Sprintf("command is after the demo's last tick %d and never runs", s.options.LastTick))
+			}
+		}
+	}
+}
+
+// seekTo moves playback to tick once the current tick's commands are done.
+func (s *simulator) seekTo(tick int) {
+	if s.recording {
+		s.take.Spans = append(s.take.Spans, [2]int{s.spanStart, s.tick})
+		s.recorded = max(s.recorded, s.tick)
+		if tick < s.recorded {
+			s.issue(LintWarning, fmt.Sprintf("seek back to tick %d re-records ticks already in this take", tick))
+		}
+		s.spanStart = tick
+	}
+	s.pos = tick
+}
+
+func (s *simulator) nextTick(pos int) (int, bool) {
+	next, ok := 0, false
+	for _, cmd := range s.schedule {
+		if cmd.Tick >= pos && (!ok || cmd.Tick < next) {
+			next, ok = cmd.Tick, true
+		}
+	}
+	return next, ok
+}
+
+// entriesAt snapshots the entries of tick, since running them may add or
+// clear entries.
+func (s *simulator) entriesAt(tick int) []scheduledCommand {
+	var entries []scheduledCommand
+	for _, cmd := range s.schedule {
+		if cmd.Tick == tick {
+			entries = append(entries, cmd)
+		}
+	}
+	return entries
+}
+
+func (s *simulator) run(command string, depth int) {
+	if s.stopped {
+		return
+	}
+	name, rest := splitWord(command)
+	if name == "" {
+		return
+	}
+	if body, ok := s.aliases[name]; ok {
+		if depth >= lintMaxAliasDepth {
+			s.issue(LintError, fmt.Sprintf("alias %s expands too deep", name))
+			return
+		}
+		for _, inner := range splitCommands(body) {
+			s.run(inner, depth+1)
+		}
+		return
+	}
+
+	switch name {
+	case "alias":
+		alias, value := splitWord(rest)
+		if alias != "" {
+			s.aliases[alias] = unquote(value)
+		}
+	case "mirv_cmd":
+		s.runMirvCmd(rest)
+	case "mirv_streams":
+		switch strings.Join(strings.Fields(rest), " ") {
+		case "record start":
+			s.startRecording()
+		case "record end":
+			s.endRecording()
+		}
+	case "demo_gototick":
+		if tick, err := strconv.Atoi(strings.TrimSpace(rest)); err == nil {
+			s.seek = tick
+		} else {
+			s.issue(LintError, fmt.Sprintf("demo_gototick needs a tick, got %q", rest))
+		}
+	case "disconnect":
+		s.stopped = true
+	case "exec":
+		name := strings.TrimSpace(unquote(rest))
+		if s.options.Exec != nil {
+			if content, ok := s.options.Exec(name); ok {
+				s.exec(name, content, depth)
+				return
+			}
+		}
+		s.issue(LintNote, fmt.Sprintf("playback continues in exec %s; lint that script separately", name))
+		s.stopped = true
+	case "playdemo":
+		s.issue(LintNote, fmt.Sprintf("playback continues in %s %s; lint that script separately", name, strings.TrimSpace(rest)))
+		s.stopped = true
+	}
+}
+
+// exec runs a part script's lines in place, then returns to the line that
+// exec'd it.
+func (s *simulator) exec(name, content string, depth int) {
+	if depth >= lintMaxAliasDepth {
+		s.issue(LintError, fmt.Sprintf("exec %s nests too deep", name))
+		return
+	}
+	script, line := s.script, s.line
+	s.script = name
+	for i, text := range strings.Split(content, "\n") {
+		s.line = i + 1
+		if !s.checkQuotes(text) {
+			continue
+		}
+		for _, command := range splitCommands(text) {
+			s.run(command, depth+1)
+		}
+		if s.stopped {
+			break
+		}
+	}
+	s.script, s.line = script, line
+}
+
+func (s *simulator) runMirvCmd(rest string) {
+	sub, args := splitWord(rest)
+	switch sub {
+	case "clear":
+		s.schedule = nil
+	case "addAtTick":
+		tickRaw, body := splitWord(args)
+		tick, err := strconv.Atoi(tickRaw)
+		if err != nil {
+			s.issue(LintError, fmt.Sprintf("mirv_cmd addAtTick needs a tick, got %q", tickRaw))
+			return
+		}
+		s.schedule = append(s.schedule, scheduledCommand{Tick: tick, Body: unquote(body), Script: s.script, Line: s.line})
+	}
+}
+
+func (s *simulator) startRecording() {
+	if s.recording {
+		s.issue(LintError, "record start while already recording; the takes overlap")
+		return
+	}
+	if s.options.LastTick > 0 && s.tick > s.options.LastTick {
+		s.issue(LintError, fmt.Sprintf("recording starts after the demo's last tick %d", s.options.LastTick))
+	}
+	s.recording = true
+	s.take = SimulatedTake{}
+	s.spanStart = max(s.tick, s.pos)
+	s.recorded = s.spanStart
+}
+
+func (s *simulator) endRecording() {
+	if !s.recording {
+		// The setup ends any recording left over from an earlier run.
+		return
+	}
+	s.closeTake(s.tick)
+}
+
+func (s *simulator) closeTake(tick int) {
+	s.take.Spans = append(s.take.Spans, [2]int{s.spanStart, tick})
+	if s.options.LastTick > 0 && tick > s.options.LastTick {
+		s.issue(LintError, fmt.Sprintf("recording runs past the demo's last tick %d", s.options.LastTick))
+	}
+	s.report.Takes = append(s.report.Takes, s.take)
+	s.recording = false
+}
+
+// checkQuotes reports lines the console would mis-split: an odd number of
+// quotes, or a quoted mirv_cmd or alias body with quotes inside it.
+func (s *simulator) checkQuotes(line string) bool {
+	quotes := strings.Count(line, `"`)
+	if quotes%2 != 0 {
+		s.issue(LintError, "unbalanced quotes")
+		return false
+	}
+	name, _ := splitWord(strings.TrimSpace(line))
+	if (name == "mirv_cmd" || name == "alias") && quotes > 2 {
+		s.issue(LintError, "nested quotes; the console ends the quoted body at the first inner quote")
+		return false
+	}
+	return true
+}
+
+// issue records a finding once, however often a seek replays its command.
+func (s *simulator) issue(severity LintSeverity, message string) {
+	issue := LintIssue{Script: s.script, Line: s.line, Tick: s.tick, Severity: severity, Message: message}
+	if s.seen[issue] {
+		return
+	}
+	s.seen[issue] = true
+	s.report.Issues = append(s.report.Issues, issue)
+}
+
+// splitCommands splits a line or alias body on ';' outside quotes.
+func splitCommands(line string) []string {
+	var commands []string
+	quoted := false
+	start := 0
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ';' && !quoted:
+			commands = append(commands, strings.TrimSpace(line[start:i]))
+			start = i + 1
+		}
+	}
+	commands = append(commands, strings.TrimSpace(line[start:]))
+	kept := commands[:0]
+	for _, command := range commands {
+		if command != "" {
+			kept = append(kept, command)
+		}
+	}
+	return kept
+}
+
+func splitWord(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	if idx := strings.IndexAny(value, " \t"); idx >= 0 {
+		return value[:idx], strings.TrimSpace(value[idx+1:])
+	}
+	return value, ""
+}
+
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
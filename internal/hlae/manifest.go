@@ -0,0 +1,399 @@
+package hlae
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// Manifest maps every recording a script produces to what is in it, so
+// post-processing can find highlights without matching takes by hand.
+type Manifest struct {
+	Demo           string              `json:"demo"`
+	SteamID        string              `json:"steamid"`
+	Target         string              `json:"target"`
+	Mode           string              `json:"mode"`
+	TickRate       float64             `json:"tick_rate"`
+	FrameRate      int                 `json:"fps"`
+	PreRollSeconds float64             `json:"pre_roll_sec"`
+	Layers         []ManifestLayer     `json:"layers"`
+	Recordings     []ManifestRecording `json:"recordings"`
+}
+
+// ManifestLayer is a stream recorded into every take, in its own folder.
+type ManifestLayer struct {
+	Name   string    `json:"name"`
+	Kind   LayerKind `json:"kind"`
+	Preset string    `json:"preset"`
+}
+
+// ManifestRecording is one expected HLAE take folder; Streams holds the
+// folder of each layer inside it, in Layers order. Demo is set in the
+// manifest of a chain.
+type ManifestRecording struct {
+	Demo       string              `json:"demo,omitempty"`
+	Folder     string              `json:"folder"`
+	Streams    []string            `json:"streams"`
+	Duration   float64             `json:"duration_sec"`
+	ReplaySlot int                 `json:"replay_slot,omitempty"`
+	Cuts       []ManifestCut       `json:"cuts"`
+	Highlights []ManifestHighlight `json:"highlights"`
+}
+
+// ManifestCut is a stretch of demo played back-to-back inside a recording;
+// a montage has one per segment and replay, a clip more when it jumps or
+// slows down.
+type ManifestCut struct {
+	TickStart  int     `json:"tick_start"`
+	TickEnd    int     `json:"tick_end"`
+	At         float64 `json:"at_sec"`
+	Duration   float64 `json:"duration_sec"`
+	Speed      float64 `json:"speed"`
+	ReplaySlot int     `json:"replay_slot,omitempty"`
+}
+
+// ManifestHighlight places a highlight inside a recording: PreRoll is where
+// its first kill plays, KillTimes where each of its kills in the recording
+// plays, both in seconds from the start of the recording. Kills, Weapon,
+// Victims (their names) and Meta are copied from the highlight for captions.
+type ManifestHighlight struct {
+	Type      model.HighlightType `json:"type"`
+	Round     int                 `json:"round"`
+	TickStart int                 `json:"tick_start"`
+	TickEnd   int                 `json:"tick_end"`
+	KillTicks []int               `json:"kill_ticks"`
+	PreRoll   float64             `json:"pre_roll_sec"`
+	KillTimes []float64           `json:"kill_times_sec"`
+	Kills     int                 `json:"kills,omitempty"`
+	Weapon    string              `json:"weapon,omitempty"`
+	Victims   []string            `json:"victims,omitempty"`
+	Meta      map[string]string   `json:"meta,omitempty"`
+}
+
+// Continues reports whether next plays on from prev inside one segment of
+// r: straight on, or jumping forward between the kills of one highlight.
+func (r ManifestRecording) Continues(prev, next ManifestCut) bool {
+	if prev.ReplaySlot != next.ReplaySlot {
+		return false
+	}
+	if next.TickStart == prev.TickEnd {
+		return true
+	}
+	for _, h := range r.Highlights {
+		if prev.TickEnd > h.TickStart && next.TickStart < h.TickEnd && next.TickStart > prev.TickEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// timelineSpan is a stretch of demo ticks played back-to-back in a
+// recording, at Scale speed (1 = real time).
+type timelineSpan struct {
+	From       int
+	To         int
+	Scale      float64
+	ReplaySlot int
+}
+
+// recordingPlan is one take a script produces, in recording order.
+type recordingPlan struct {
+	Name       string
+	Highlights []model.Highlight
+	Spans      []timelineSpan
+	ReplaySlot int
+}
+
+// BuildManifest describes the recordings the clips or montage script of the
+// same selection produces. Clip folders use the per-segment record names
+// when the record path can be passed unquoted or NameExec is set, numbered
+// takes otherwise.
+func (b *ScriptBuilder) BuildManifest(result model.HighlightResult, types model.Selection, name string, mode Mode) Manifest {
+	return b.manifest(result, types, name, mode, 0)
+}
+
+// manifest numbers unnamed takes from take0000 under the script's record
+// base. Without a base, takes land in HLAE's shared folder, where the count
+// goes on across the scripts of a chain from firstTake.
+func (b *ScriptBuilder) manifest(result model.HighlightResult, types model.Selection, name string, mode Mode, firstTake int) Manifest {
+	clips := mode == ModeClips
+	base := b.recordBase(result.SteamID, name)
+	manifest := Manifest{
+		Demo:           result.Demo,
+		SteamID:        result.SteamID,
+		Target:         name,
+		Mode:           mode.String(),
+		TickRate:       result.TickRate,
+		FrameRate:      b.frameRate(),
+		PreRollSeconds: tickSeconds(b.StartOffsetTicks, result.TickRate),
+		Recordings:     make([]ManifestRecording, 0),
+	}
+	for _, layer := range b.streamLayers() {
+		manifest.Layers = append(manifest.Layers, ManifestLayer{Name: layer.Name, Kind: layer.Kind, Preset: b.layerPreset(layer)})
+	}
+
+	plans := b.planRecordings(b.resolveSegments(result.Highlights, types), clips)
+	if base != "" {
+		firstTake = 0
+	}
+	for i, plan := range plans {
+		folder := joinRecordPath(base, fmt.Sprintf("take%04d", firstTake+i))
+		if clips && b.recordNameCommands(base, plan.Name) != nil {
+			folder = joinRecordPath(base, plan.Name, "take0000")
+		}
+		recording := plan.manifestRecording(folder, result.TickRate)
+		for _, layer := range manifest.Layers {
+			recording.Streams = append(recording.Streams, joinRecordPath(folder, layer.Name))
+		}
+		manifest.Recordings = append(manifest.Recordings, recording)
+	}
+	return manifest
+}
+
+func (p recordingPlan) manifestRecording(folder string, tickRate float64) ManifestRecording {
+	recording := ManifestRecording{
+		Folder:     folder,
+		ReplaySlot: p.ReplaySlot,
+		Cuts:       make([]ManifestCut, 0, len(p.Spans)),
+		Highlights: make([]ManifestHighlight, 0, len(p.Highlights)),
+	}
+	at := 0.0
+	for _, span := range p.Spans {
+		duration := spanSeconds(span, tickRate)
+		recording.Cuts = append(recording.Cuts, ManifestCut{
+			TickStart:  span.From,
+			TickEnd:    span.To,
+			At:         roundSeconds(at),
+			Duration:   roundSeconds(duration),
+			Speed:      span.Scale,
+			ReplaySlot: span.ReplaySlot,
+		})
+		at += duration
+	}
+	recording.Duration = roundSeconds(at)
+
+	for _, h := range p.Highlights {
+		entry := ManifestHighlight{
+			Type:      h.Type,
+			Round:     h.Round,
+			TickStart: h.TickStart,
+			TickEnd:   h.TickEnd,
+			KillTicks: make([]int, 0),
+			KillTimes: make([]float64, 0),
+			Kills:     h.Kills,
+			Weapon:    h.Weapon,
+			Victims:   h.VictimNames,
+			Meta:      h.Meta,
+		}
+		for _, tick := range actionTicks(h) {
+			if offset, ok := offsetOf(p.Spans, tick, tickRate); ok {
+				entry.KillTicks = append(entry.KillTicks, tick)
+				entry.KillTimes = append(entry.KillTimes, roundSeconds(offset))
+			}
+		}
+		if len(entry.KillTimes) == 0 {
+			continue
+		}
+		entry.PreRoll = entry.KillTimes[0]
+		recording.Highlights = append(recording.Highlights, entry)
+	}
+	return recording
+}
+
+// planRecordings lays out the takes of a script: per segment and per replay
+// for clips, one take of every segment and replay in order for a montage.
+func (b *ScriptBuilder) planRecordings(segs []recordingSegment, clips bool) []recordingPlan {
+	var plans []recordingPlan
+	montage := recordingPlan{}
+	seen := make(map[string]bool)
+	for _, seg := range segs {
+		spans := b.segmentSpans(seg)
+		replays := b.victimReplays(seg)
+		if clips {
+			plans = append(plans, recordingPlan{Name: segmentRecordName(seg), Highlights: seg.Highlights, Spans: spans})
+			for k, replay := range replays {
+				plans = append(plans, recordingPlan{
+					Name:       replayRecordName(seg, k),
+					Highlights: seg.Highlights,
+					Spans:      []timelineSpan{{From: replay.StartTick, To: replay.EndTick, Scale: 1, ReplaySlot: replay.PlayerSlot}},
+					ReplaySlot: replay.PlayerSlot,
+				})
+			}
+			continue
+		}
+
+		// A kill streak spans several segments; list it once.
+		for _, h := range seg.Highlights {
+			key := fmt.Sprintf("%s/%s/%d", h.SteamID, h.Type, h.TickStart)
+			if !seen[key] {
+				seen[key] = true
+				montage.Highlights = append(montage.Highlights, h)
+			}
+		}
+		montage.Spans = append(montage.Spans, spans...)
+		for _, replay := range replays {
+			montage.Spans = append(montage.Spans, timelineSpan{From: replay.StartTick, To: replay.EndTick, Scale: 1, ReplaySlot: replay.PlayerSlot})
+		}
+	}
+	if !clips && len(segs) > 0 {
+		plans = append(plans, montage)
+	}
+	return plans
+}
+
+// segmentSpans is what playing seg records: cut by intra-segment jumps and
+// split where slow motion changes the speed.
+func (b *ScriptBuilder) segmentSpans(seg recordingSegment) []timelineSpan {
+	var played [][2]int
+	from := seg.StartTick
+	for _, jump := range b.resolveIntraSegmentJumps(seg) {
+		played = append(played, [2]int{from, jump.AtTick})
+		from = jump.SeekTick
+	}
+	played = append(played, [2]int{from, seg.EndTick})
+
+	var windows [][2]int
+	if b.slowMotionEnabled() {
+		windows = b.slowMotionWindows(seg)
+	}
+	var spans []timelineSpan
+	for _, part := range played {
+		cursor := part[0]
+		for _, window := range windows {
+			lo, hi := max(window[0], cursor), min(window[1], part[1])
+			if lo >= hi {
+				continue
+			}
+			if lo > cursor {
+				spans = append(spans, timelineSpan{From: cursor, To: lo, Scale: 1})
+			}
+			spans = append(spans, timelineSpan{From: lo, To: hi, Scale: b.SlowMotionScale})
+			cursor = hi
+		}
+		if cursor < part[1] {
+			spans = append(spans, timelineSpan{From: cursor, To: part[1], Scale: 1})
+		}
+	}
+	return spans
+}
+
+// offsetOf returns where tick first plays in spans, in recording seconds.
+func offsetOf(spans []timelineSpan, tick int, tickRate float64) (float64, bool) {
+	at := 0.0
+	for _, span := range spans {
+		if tick >= span.From && tick <= span.To {
+			return at + spanSeconds(timelineSpan{From: span.From, To: tick, Scale: span.Scale}, tickRate), true
+		}
+		at += spanSeconds(span, tickRate)
+	}
+	return 0, false
+}
+
+// spanSeconds is how long span lasts in the recording: slowed playback
+// stretches it by 1/Scale.
+func spanSeconds(span timelineSpan, tickRate float64) float64 {
+	if span.Scale <= 0 {
+		return 0
+	}
+	return tickSeconds(span.To-span.From, tickRate) / span.Scale
+}
+
+func tickSeconds(ticks int, tickRate float64) float64 {
+	if tickRate <= 0 {
+		return 0
+	}
+	return float64(ticks) / tickRate
+}
+
+func roundSeconds(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// actionTicks are h's kill ticks, or its start for single-kill highlights.
+func actionTicks(h model.Highlight) []int {
+	if len(h.KillTicks) > 0 {
+		return h.KillTicks
+	}
+	return []int{h.TickStart}
+}
+
+// segmentRecordName names a clip folder after the highlight that opens the
+// segment: round, type, kill count and first kill tick, e.g.
+// r16_round_multikill_3k_112258.
+func segmentRecordName(seg recordingSegment) string {
+	h := seg.Highlights[0]
+	kills := max(h.Kills, 1)
+	first := h.TickStart
+	if len(h.KillTicks) > 0 {
+		inSegment := 0
+		for _, tick := range h.KillTicks {
+			if tick < seg.StartTick || tick > seg.EndTick {
+				continue
+			}
+			if inSegment == 0 {
+				first = tick
+			}
+			inSegment++
+		}
+		kills = max(inSegment, 1)
+	}
+	return sanitizeNameToken(fmt.Sprintf("r%d_%s_%dk_%d", h.Round, h.Type, kills, first))
+}
+
+func replayRecordName(seg recordingSegment, replay int) string {
+	return fmt.Sprintf("%s_replay%d", segmentRecordName(seg), replay+1)
+}
+
+// recordNameCommands points the next take at base/name. The command runs
+// inside a quoted mirv_cmd, so a base that needs quotes of its own is named
+// from the take's script under NameExec instead; without one, takes keep
+// the script's shared record name.
+func (b *ScriptBuilder) recordNameCommands(base string, name string) []string {
+	if base == "" || name == "" {
+		return nil
+	}
+	if unquotedSafe(base) {
+		return []string{fmt.Sprintf("mirv_streams record name %s/%s", base, name)}
+	}
+	if b.NameExec == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("exec %s/%s", b.NameExec, name)}
+}
+
+// recordNameFiles writes the record name script of every clip take into
+// dir when the record path needs quotes, for recordNameCommands to exec.
+func (b *ScriptBuilder) recordNameFiles(result model.HighlightResult, types model.Selection, name string, dir string) []File {
+	base := b.recordBase(result.SteamID, name)
+	if base == "" || unquotedSafe(base) || b.NameExec == "" {
+		return nil
+	}
+	var files []File
+	for _, plan := range b.planRecordings(b.resolveSegments(result.Highlights, types), true) {
+		files = append(files, File{
+			Path:    filepath.Join(dir, plan.Name+".cfg"),
+			Content: fmt.Sprintf("mirv_streams record name \"%s/%s\"\n", base, plan.Name),
+		})
+	}
+	return files
+}
+
+// unquotedSafe reports whether value can be passed to a console command
+// without quotes, as the commands inside mirv_cmd and alias bodies must.
+func unquotedSafe(value string) bool {
+	return value != "" && !strings.ContainsAny(value, " \t;\"'")
+}
+
+// joinRecordPath joins non-empty parts with "/" like the record name does.
+func joinRecordPath(parts ...string) string {
+	kept := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "/")
+}
@@ -1,10 +1,18 @@
 package hlae
 
 import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
 )
 
-// Options holds the shared rendering settings applied to every render target.
+// Options holds the rendering settings shared by every render target that
+// does not override them.
 type Options struct {
 	FrameRate       int
 	OutputPath      string
@@ -12,6 +20,33 @@ type Options struct {
 	PreRollSeconds  int
 	PostRollSeconds int
 	KillGapSeconds  int
+	// SlowMotionScale slows playback to this speed (e.g. 0.3) around every
+	// kill tick; 0 disables slow motion.
+	SlowMotionScale float64
+	// SlowMotionSeconds is the slow-motion window on each side of a kill.
+	SlowMotionSeconds float64
+	// ReplaySeconds re-plays each kill from the victim's POV (the killer's in
+	// a deaths reel), this many seconds on each side; 0 disables replays.
+	ReplaySeconds int
+	// ReplayTypes limits replays to these highlight types (empty = all).
+	ReplayTypes model.Selection
+	// IntroTypes gets a free-camera mirv_campath intro of IntroSeconds,
+	// flown in IntroStyle, before segments of these types (empty = none).
+	IntroTypes   model.Selection
+	IntroStyle   IntroStyle
+	IntroSeconds int
+	// MaxLines splits a script longer than this into a setup script and
+	// part scripts of at most this many lines each; 0 never splits.
+	MaxLines int
+	// HUD is what the game draws over the recordings; the zero value is the
+	// killfeed preset.
+	HUD HUD
+}
+
+// File is one generated output: a script or a file the script loads.
+type File struct {
+	Path    string
+	Content string
 }
 
 // Mode selects how a target packages its highlights.
@@ -24,24 +59,193 @@ const (
 	ModeMontage
 )
 
+func (m Mode) String() string {
+	if m == ModeMontage {
+		return "montage"
+	}
+	return "clips"
+}
+
 // Target is a single .cfg to generate: a render mode over a set of highlight
-// types. An empty Types selects all types.
+// types. An empty Types selects all types; empty Layers records the screen
+// stream alone. A non-nil Options replaces the shared options for this
+// target. Recorder names the backend that renders it (see package
+// recorder); empty is HLAE.
 type Target struct {
-	Mode  Mode
-	Types model.Selection
-	Path  string
-	Name  string
+	Mode     Mode
+	Types    model.Selection
+	Path     string
+	Name     string
+	Layers   []StreamLayer
+	Options  *Options
+	Recorder string
+}
+
+// options is what the target renders with: its own options, or the shared
+// ones.
+func (t Target) options(shared Options) Options {
+	if t.Options != nil {
+		return *t.Options
+	}
+	return shared
+}
+
+// BuildTarget renders a target's script, followed by the part scripts it
+// execs when split, the campath file it loads when the target has intros
+// and the manifest of its recordings.
+func BuildTarget(result model.HighlightResult, options Options, target Target) ([]File, error) {
+	files, manifest, err := renderTarget(result, options, target, renderContext{
+		ExecName: strings.TrimSuffix(filepath.Base(target.Path), filepath.Ext(target.Path)),
+		Date:     time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	file, err := manifestFile(target.Path, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, file), nil
+}
+
+// Segment is a span of demo ticks recorded in one piece from PlayerSlot's
+// POV, for recorders that drive the game without mirv commands.
+type Segment struct {
+	PlayerSlot int
+	StartTick  int
+	EndTick    int
+	Highlights []model.Highlight
 }
 
-func BuildTarget(result model.HighlightResult, options Options, target Target) string {
+// PlanSegments resolves the segments target records over result with the
+// options it renders with, which it returns too: its highlights padded with
+// the pre- and post-roll, merged where they overlap. A merged segment whose
+// POV switches to another player is split at each switch, so every segment
+// keeps one player. Camera intros are left out, since they need
+// mirv_campath.
+func PlanSegments(result model.HighlightResult, options Options, target Target) ([]Segment, Options) {
+	options = target.options(options)
 	builder := configuredBuilder(result, options)
-	if target.Mode == ModeMontage {
-		return builder.BuildMontage(result, target.Types, target.Name)
+	builder.IntroTicks = 0
+	segs := builder.resolveSegments(result.Highlights, target.Types)
+	planned := make([]Segment, 0, len(segs))
+	for _, seg := range segs {
+		planned = append(planned, splitAtSwitches(seg)...)
+	}
+	return planned, options
+}
+
+// splitAtSwitches cuts seg at each POV switch. A highlight goes to the span
+// of its player that its switch opened; highlights of no known player stay
+// in the span they merged into.
+func splitAtSwitches(seg recordingSegment) []Segment {
+	spans := []Segment{{PlayerSlot: seg.PlayerSlot, StartTick: seg.StartTick, EndTick: seg.EndTick}}
+	for _, sw := range seg.Switches {
+		spans[len(spans)-1].EndTick = sw.AtTick
+		spans = append(spans, Segment{PlayerSlot: sw.PlayerSlot, StartTick: sw.AtTick, EndTick: seg.EndTick})
+	}
+	current := 0
+	for _, h := range seg.Highlights {
+		if h.PlayerSlot > 0 && h.PlayerSlot != spans[current].PlayerSlot && current+1 < len(spans) {
+			current++
+		}
+		spans[current].Highlights = append(spans[current].Highlights, h)
 	}
-	if options.KillGapSeconds > 0 && result.TickRate > 0 {
+	return spans
+}
+
+// renderContext is where a target's script runs from. ExecName is the
+// script's exec name (its path under csgo/cfg, without extension), which
+// part and record name scripts are exec'd under. A non-empty Next makes it a
+// chained script, whose takes without a record base are numbered from
+// FirstTake. Date is the date folder of the recordings, fixed once so every
+// file agrees.
+type renderContext struct {
+	ExecName  string
+	Next      string
+	FirstTake int
+	Date      time.Time
+}
+
+// renderTarget renders a target's script, its parts and campath file, and
+// describes its recordings.
+func renderTarget(result model.HighlightResult, options Options, target Target, ctx renderContext) ([]File, Manifest, error) {
+	options = target.options(options)
+	builder := configuredBuilder(result, options)
+	builder.Layers = target.Layers
+	builder.Next = ctx.Next
+	builder.Date = ctx.Date
+	if target.Mode == ModeClips && builder.OutputPath != "" && !unquotedSafe(builder.recordBase(result.SteamID, target.Name)) {
+		if !unquotedSafe(ctx.ExecName) {
+			return nil, Manifest{}, fmt.Errorf("clips %q: the record path needs quotes, so takes are named from exec'd scripts and the script name must not contain spaces, ';' or quotes", target.Path)
+		}
+		builder.NameExec = ctx.ExecName + "/names"
+	}
+	campathPath := ""
+	if builder.IntroTicks > 0 {
+		campathPath = strings.TrimSuffix(target.Path, filepath.Ext(target.Path)) + ".campath.xml"
+		builder.CampathPath = consolePath(campathPath)
+	}
+	if target.Mode == ModeClips && options.KillGapSeconds > 0 && result.TickRate > 0 {
 		builder.KillGapTicks = int(result.TickRate * float64(options.KillGapSeconds))
 	}
-	return builder.BuildClips(result, target.Types, target.Name)
+
+	build := builder.buildClips
+	if target.Mode == ModeMontage {
+		build = builder.buildMontage
+	}
+	script, _ := build(result, target.Types, target.Name, scriptSplit{})
+	var parts []string
+	if options.MaxLines > 0 && strings.Count(script, "\n") > options.MaxLines {
+		if !unquotedSafe(ctx.ExecName) {
+			return nil, Manifest{}, fmt.Errorf("split %q: script name must not contain spaces, ';' or quotes", target.Path)
+		}
+		script, parts = build(result, target.Types, target.Name, scriptSplit{
+			MaxLines: options.MaxLines,
+			Exec: func(part int) string {
+				return fmt.Sprintf("%s/%s", ctx.ExecName, partName(part))
+			},
+		})
+	}
+
+	files := []File{{Path: target.Path, Content: script}}
+	partDir := strings.TrimSuffix(target.Path, filepath.Ext(target.Path))
+	for i, part := range parts {
+		files = append(files, File{Path: filepath.Join(partDir, partName(i+1)+".cfg"), Content: part})
+	}
+	files = append(files, builder.recordNameFiles(result, target.Types, target.Name, filepath.Join(partDir, "names"))...)
+	if campathPath != "" {
+		if campath := builder.BuildCampath(result, target.Types); campath != "" {
+			files = append(files, File{Path: campathPath, Content: campath})
+		}
+	}
+	return files, builder.manifest(result, target.Types, target.Name, target.Mode, ctx.FirstTake), nil
+}
+
+func partName(part int) string {
+	return fmt.Sprintf("part%02d", part)
+}
+
+func manifestFile(scriptPath string, manifest Manifest) (File, error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return File{}, fmt.Errorf("encode manifest: %w", err)
+	}
+	return File{Path: ManifestPath(scriptPath), Content: string(data) + "\n"}, nil
+}
+
+// ManifestPath is where the recording manifest of the script at path goes.
+func ManifestPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".manifest.json"
+}
+
+// consolePath makes path absolute with forward slashes, since the game
+// resolves relative paths against its own directory.
+func consolePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.ToSlash(path)
 }
 
 func configuredBuilder(result model.HighlightResult, options Options) *ScriptBuilder {
@@ -50,5 +254,19 @@ func configuredBuilder(result model.HighlightResult, options Options) *ScriptBui
 	builder.FrameRate = options.FrameRate
 	builder.OutputPath = options.OutputPath
 	builder.FFmpegPreset = options.FFmpegPreset
+	builder.HUD = options.HUD
+	if options.SlowMotionScale > 0 && result.TickRate > 0 {
+		builder.SlowMotionScale = options.SlowMotionScale
+		builder.SlowMotionTicks = int(result.TickRate * options.SlowMotionSeconds)
+	}
+	if options.ReplaySeconds > 0 && result.TickRate > 0 {
+		builder.ReplayTicks = int(result.TickRate * float64(options.ReplaySeconds))
+		builder.ReplayTypes = options.ReplayTypes
+	}
+	if options.IntroSeconds > 0 && len(options.IntroTypes) > 0 && result.TickRate > 0 {
+		builder.IntroTicks = int(result.TickRate * float64(options.IntroSeconds))
+		builder.IntroTypes = options.IntroTypes
+		builder.IntroStyle = cmp.Or(options.IntroStyle, IntroOrbit)
+	}
 	return builder
 }
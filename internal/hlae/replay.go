@@ -0,0 +1,192 @@
+package hlae
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// replayNop is the empty alias disarmed tick commands point to.
+const replayNop = "hlr_nop"
+
+// tickCommand is one mirv_cmd addAtTick entry. In a segment's "after" list
+// Tick is an offset from the end of the segment (or of its last replay).
+type tickCommand struct {
+	Tick int
+	Body string
+}
+
+// victimReplay re-plays [StartTick, EndTick] of a kill from the other
+// player's POV once the segment itself has played.
+type victimReplay struct {
+	StartTick  int
+	EndTick    int
+	PlayerSlot int
+}
+
+// gatedCommand is a tick command that only fires in one playback phase of its
+// segment: phase 0 is the segment itself, phase k its k-th replay.
+type gatedCommand struct {
+	tickCommand
+	Name  string
+	Phase int
+}
+
+// writeSegmentCommands writes a segment's tick commands followed by what runs
+// once it is done (after). With victim replays, the segment seeks back into
+// itself after playing, so the mirv_cmd entries of the replayed ticks would
+// fire again; those entries call aliases instead, and each phase switch
+// re-points every alias to its body or to a no-op. Clips record every replay
+// as its own take under base, montages splice it in via the seek jump.
+func (b *ScriptBuilder) writeSegmentCommands(w *strings.Builder, index int, seg recordingSegment, cmds []tickCommand, after []tickCommand, clips bool, base string) {
+	replays := b.victimReplays(seg)
+	if len(replays) == 0 {
+		for _, cmd := range cmds {
+			writeTickCommand(w, cmd.Tick, cmd.Body)
+		}
+		for _, cmd := range after {
+			writeTickCommand(w, seg.EndTick+cmd.Tick, cmd.Body)
+		}
+		return
+	}
+
+	phases := make([][]tickCommand, len(replays)+1)
+	phases[0] = append(cmds, tickCommand{Tick: seg.EndTick + 1, Body: replayPhaseAlias(index, 1)})
+	for k, replay := range replays {
+		var phase []tickCommand
+		if clips {
+			startParts := append(b.recordNameCommands(base, replayRecordName(seg, k)), povCommandsBySlot(replay.PlayerSlot)...)
+			startParts = append(startParts,
+				fmt.Sprintf("host_framerate %d", b.frameRate()),
+				"mirv_streams record start",
+			)
+			phase = append(phase,
+				tickCommand{Tick: replay.StartTick, Body: joinCommands(startParts...)},
+				tickCommand{Tick: replay.EndTick, Body: b.stopCommand()},
+			)
+		}
+		if k+1 < len(replays) {
+			phase = append(phase, tickCommand{Tick: replay.EndTick + 1, Body: replayPhaseAlias(index, k+2)})
+		} else {
+			for _, cmd := range after {
+				phase = append(phase, tickCommand{Tick: replay.EndTick + cmd.Tick, Body: cmd.Body})
+			}
+		}
+		phases[k+1] = phase
+	}
+
+	replayed := func(tick int) bool {
+		for _, replay := range replays {
+			if tick >= seekTickBefore(replay.StartTick) && tick <= replay.EndTick+2 {
+				return true
+			}
+		}
+		return false
+	}
+
+	var gates []gatedCommand
+	for phase, phaseCmds := range phases {
+		for _, cmd := range phaseCmds {
+			if phase == 0 && !replayed(cmd.Tick) {
+				writeTickCommand(w, cmd.Tick, cmd.Body)
+				continue
+			}
+			gate := gatedCommand{tickCommand: cmd, Name: fmt.Sprintf("hlr%d_%d", index, len(gates)+1), Phase: phase}
+			gates = append(gates, gate)
+		}
+	}
+
+	// Commands at one tick run in the order they were added. Phase switches go
+	// last, later phases first, so the gates a switch arms for the next phase
+	// do not fire on the tick that is being left.
+	sort.SliceStable(gates, func(i, j int) bool {
+		if gates[i].Tick != gates[j].Tick {
+			return gates[i].Tick < gates[j].Tick
+		}
+		switchI, switchJ := isPhaseSwitch(gates[i].Body, index), isPhaseSwitch(gates[j].Body, index)
+		if switchI != switchJ {
+			return switchJ
+		}
+		return switchI && gates[i].Phase > gates[j].Phase
+	})
+
+	for k, replay := range replays {
+		parts := make([]string, 0, len(gates)+6)
+		for _, gate := range gates {
+			target := replayNop
+			if gate.Phase == k+1 {
+				target = gate.Name + "_on"
+			}
+			parts = append(parts, fmt.Sprintf("alias %s %s", gate.Name, target))
+		}
+		if !clips && b.slowMotionEnabled() {
+			parts = append(parts, b.normalSpeedCommand())
+		}
+		parts = append(parts, buildSeekJumpCommand(seekTickBefore(replay.StartTick), replay.PlayerSlot))
+		writeCommandLine(w, fmt.Sprintf("alias %s \"%s\"", replayPhaseAlias(index, k+1), joinCommands(parts...)))
+	}
+	for _, gate := range gates {
+		initial := replayNop
+		if gate.Phase == 0 {
+			initial = gate.Name + "_on"
+		}
+		writeCommandLine(w, fmt.Sprintf("alias %s_on \"%s\"", gate.Name, gate.Body))
+		writeCommandLine(w, fmt.Sprintf("alias %s %s", gate.Name, initial))
+		writeTickCommand(w, gate.Tick, gate.Name)
+	}
+}
+
+// victimReplays lists the kills of seg to re-play from the other player's
+// POV, each clamped to the segment and de-duplicated across highlights that
+// share a kill.
+func (b *ScriptBuilder) victimReplays(seg recordingSegment) []victimReplay {
+	if !b.replaysEnabled() {
+		return nil
+	}
+	seen := make(map[[2]int]bool)
+	var replays []victimReplay
+	for _, h := range seg.Highlights {
+		if !b.ReplayTypes.Enabled(h.Type) {
+			continue
+		}
+		ticks := h.KillTicks
+		if len(ticks) == 0 {
+			ticks = []int{h.TickStart}
+		}
+		for i, tick := range ticks {
+			if i >= len(h.ReplaySlots) || h.ReplaySlots[i] <= 0 || tick < seg.StartTick || tick > seg.EndTick {
+				continue
+			}
+			key := [2]int{tick, h.ReplaySlots[i]}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			replays = append(replays, victimReplay{
+				StartTick:  max(tick-b.ReplayTicks, seg.StartTick),
+				EndTick:    min(tick+b.ReplayTicks, seg.EndTick),
+				PlayerSlot: h.ReplaySlots[i],
+			})
+		}
+	}
+	sort.SliceStable(replays, func(i, j int) bool {
+		return replays[i].StartTick < replays[j].StartTick
+	})
+	return replays
+}
+
+func (b *ScriptBuilder) replaysEnabled() bool {
+	return b.ReplayTicks > 0
+}
+
+func replayPhaseAlias(segment int, replay int) string {
+	return fmt.Sprintf("hlr%d_p%d", segment, replay)
+}
+
+func isPhaseSwitch(body string, segment int) bool {
+	return strings.HasPrefix(body, fmt.Sprintf("hlr%d_p", segment))
+}
+
+func writeTickCommand(w *strings.Builder, tick int, body string) {
+	writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"%s\"", tick, body))
+}
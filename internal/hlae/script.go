@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,39 @@ type ScriptBuilder struct {
 	FrameRate        int
 	OutputPath       string
 	FFmpegPreset     string
+	// SlowMotionScale is the host_timescale used around each kill tick;
+	// 0 (or >= 1) keeps playback at normal speed.
+	SlowMotionScale float64
+	// SlowMotionTicks is how far the slow-motion window reaches on each side
+	// of a kill tick.
+	SlowMotionTicks int
+	// ReplayTicks re-plays this many ticks on each side of a kill from the
+	// victim's POV (the killer's for a death) after its segment; 0 disables.
+	ReplayTicks int
+	// ReplayTypes limits replays to these highlight types (empty = all).
+	ReplayTypes model.Selection
+	// IntroTicks prepends a free-camera intro of this length to segments
+	// opened by a highlight of IntroTypes (empty = none), flown in IntroStyle
+	// along the path BuildCampath writes to CampathPath.
+	IntroTicks  int
+	IntroTypes  model.Selection
+	IntroStyle  IntroStyle
+	CampathPath string
+	// Layers records these streams in every take instead of the screen
+	// stream alone.
+	Layers []StreamLayer
+	// HUD is what the game draws over the recordings.
+	HUD HUD
+	// Next is run after the last segment instead of disconnect, to chain
+	// scripts. A chained script is exec'd with the console closed, so it
+	// leaves the console alone.
+	Next string
+	// Date is the date folder of the recordings; zero is today.
+	Date time.Time
+	// NameExec is the exec folder of the record name scripts recordNameFiles
+	// writes, for clip takes whose record path needs quotes; empty leaves
+	// those takes numbered.
+	NameExec string
 }
 
 func NewScriptBuilder() *ScriptBuilder {
@@ -37,6 +71,17 @@ func NewScriptBuilder() *ScriptBuilder {
 		FrameRate:        defaultFrameRate,
 		OutputPath:       "",
 		FFmpegPreset:     defaultPreset,
+		SlowMotionScale:  0,
+		SlowMotionTicks:  0,
+		ReplayTicks:      0,
+		ReplayTypes:      nil,
+		IntroTicks:       0,
+		IntroTypes:       nil,
+		IntroStyle:       IntroOrbit,
+		CampathPath:      "",
+		Layers:           nil,
+		HUD:              HUD{Preset: HUDKillfeed},
+		Next:             "",
 	}
 }
 
@@ -57,12 +102,29 @@ type recordingSegment struct {
 	StartTick  int
 	EndTick    int
 	Highlights []model.Highlight
+	Switches   []povSwitch
+	Intro      *campathIntro
+}
+
+// povSwitch moves the POV to another player inside a segment, when merged
+// highlights belong to different players (team reels).
+type povSwitch struct {
+	AtTick     int
+	PlayerSlot int
+}
+
+func (s recordingSegment) currentSlot() int {
+	if len(s.Switches) > 0 {
+		return s.Switches[len(s.Switches)-1].PlayerSlot
+	}
+	return s.PlayerSlot
 }
 
 type segmentRange struct {
 	Highlight model.Highlight
 	StartTick int
 	EndTick   int
+	Intro     bool
 }
 
 type recordingJump struct {
@@ -72,25 +134,90 @@ type recordingJump struct {
 }
 
 func (b *ScriptBuilder) BuildClips(result model.HighlightResult, types model.Selection, name string) string {
+	script, _ := b.buildClips(result, types, name, scriptSplit{})
+	return script
+}
+
+func (b *ScriptBuilder) BuildMontage(result model.HighlightResult, types model.Selection, montageName string) string {
+	script, _ := b.buildMontage(result, types, montageName, scriptSplit{})
+	return script
+}
+
+// scriptSplit moves the tick commands of a script into part scripts of at
+// most MaxLines lines, exec'd by the console names Exec returns (1-based).
+// The zero value keeps everything in one script.
+type scriptSplit struct {
+	MaxLines int
+	Exec     func(part int) string
+}
+
+// segmentWriter writes the tick commands of segment i. A non-empty exec runs
+// with the jump to the next segment, to load the part script holding it.
+type segmentWriter func(w *strings.Builder, i int, exec string)
+
+func (b *ScriptBuilder) buildClips(result model.HighlightResult, types model.Selection, name string, split scriptSplit) (string, []string) {
 	var w strings.Builder
 	segs := b.resolveSegments(result.Highlights, types)
 
-	b.writeSetup(&w, result.SteamID, name)
-	b.writeTickCommands(&w, segs)
+	b.writeSetup(&w, result, name, segs)
+	parts := b.writeTickCommands(&w, segs, b.recordBase(result.SteamID, name), split)
 	b.writeFooter(&w, segs)
 
-	return w.String()
+	return w.String(), parts
 }
 
-func (b *ScriptBuilder) BuildMontage(result model.HighlightResult, types model.Selection, montageName string) string {
+func (b *ScriptBuilder) buildMontage(result model.HighlightResult, types model.Selection, montageName string, split scriptSplit) (string, []string) {
 	var w strings.Builder
 	segs := b.resolveSegments(result.Highlights, types)
 
-	b.writeSetup(&w, result.SteamID, montageName)
-	b.writeMontageCommands(&w, segs)
+	b.writeSetup(&w, result, montageName, segs)
+	parts := b.writeMontageCommands(&w, segs, split)
 	b.writeMontageFooter(&w, segs, montageName)
 
-	return w.String()
+	return w.String(), parts
+}
+
+// writeSegments writes the tick commands of count segments to w, or with a
+// split, groups whole segments into parts of at most split.MaxLines lines (a
+// segment longer than that gets a part of its own). w then execs the first
+// part, and each part execs the next along with the jump to its first
+// segment, so the commands of a part are loaded before its ticks play.
+func writeSegments(w *strings.Builder, count int, write segmentWriter, split scriptSplit) []string {
+	if split.MaxLines <= 0 || split.Exec == nil {
+		for i := range count {
+			write(w, i, "")
+		}
+		return nil
+	}
+
+	var groups [][2]int
+	start, lines := 0, 0
+	for i := range count {
+		var block strings.Builder
+		write(&block, i, "")
+		n := strings.Count(block.String(), "\n")
+		if i > start && lines+n > split.MaxLines {
+			groups = append(groups, [2]int{start, i})
+			start, lines = i, 0
+		}
+		lines += n
+	}
+	groups = append(groups, [2]int{start, count})
+
+	parts := make([]string, 0, len(groups))
+	for g, group := range groups {
+		var part strings.Builder
+		for i := group[0]; i < group[1]; i++ {
+			exec := ""
+			if i == group[1]-1 && g+1 < len(groups) {
+				exec = "exec " + split.Exec(g+2)
+			}
+			write(&part, i, exec)
+		}
+		parts = append(parts, part.String())
+	}
+	writeCommandLine(w, "exec "+split.Exec(1))
+	return parts
 }
 
 func (b *ScriptBuilder) resolveSegments(highlights []model.Highlight, types model.Selection) []recordingSegment {
@@ -99,13 +226,21 @@ func (b *ScriptBuilder) resolveSegments(highlights []model.Highlight, types mode
 		if !types.Enabled(h.Type) {
 			continue
 		}
-		start := max(h.SegmentFrom-b.StartOffsetTicks, 0)
-		end := max(h.SegmentTo+b.EndOffsetTicks, start)
-		ranges = append(ranges, segmentRange{
-			Highlight: h,
-			StartTick: start,
-			EndTick:   end,
-		})
+		for i, window := range highlightWindows(h) {
+			intro := i == 0 && b.introEnabled(h)
+			lead := b.StartOffsetTicks
+			if intro {
+				lead += b.IntroTicks
+			}
+			start := max(window[0]-lead, 0)
+			end := max(window[1]+b.EndOffsetTicks, start)
+			ranges = append(ranges, segmentRange{
+				Highlight: h,
+				StartTick: start,
+				EndTick:   end,
+				Intro:     intro,
+			})
+		}
 	}
 
 	sort.Slice(ranges, func(i, j int) bool {
@@ -118,136 +253,299 @@ func (b *ScriptBuilder) resolveSegments(highlights []model.Highlight, types mode
 	segments := make([]recordingSegment, 0, len(ranges))
 	for _, item := range ranges {
 		if len(segments) == 0 {
-			segments = append(segments, recordingSegment{
-				PlayerSlot: item.Highlight.PlayerSlot,
-				StartTick:  item.StartTick,
-				EndTick:    item.EndTick,
-				Highlights: []model.Highlight{item.Highlight},
-			})
+			segments = append(segments, b.newSegment(item))
 			continue
 		}
 
 		last := &segments[len(segments)-1]
 		if item.StartTick <= last.EndTick {
-			last.EndTick = max(last.EndTick, item.EndTick)
 			if last.PlayerSlot <= 0 && item.Highlight.PlayerSlot > 0 {
 				last.PlayerSlot = item.Highlight.PlayerSlot
+			} else if sw, ok := povSwitchFor(*last, item); ok {
+				last.Switches = append(last.Switches, sw)
 			}
+			last.EndTick = max(last.EndTick, item.EndTick)
 			last.Highlights = append(last.Highlights, item.Highlight)
 			continue
 		}
 
-		segments = append(segments, recordingSegment{
-			PlayerSlot: item.Highlight.PlayerSlot,
-			StartTick:  item.StartTick,
-			EndTick:    item.EndTick,
-			Highlights: []model.Highlight{item.Highlight},
-		})
+		segments = append(segments, b.newSegment(item))
 	}
 
 	return segments
 }
 
-func (b *ScriptBuilder) writeSetup(w *strings.Builder, steamID string, name string) {
+// newSegment opens a segment with item. Only the highlight that opens a
+// segment gets its intro; one merged into a running segment is recorded as is.
+func (b *ScriptBuilder) newSegment(item segmentRange) recordingSegment {
+	seg := recordingSegment{
+		PlayerSlot: item.Highlight.PlayerSlot,
+		StartTick:  item.StartTick,
+		EndTick:    item.EndTick,
+		Highlights: []model.Highlight{item.Highlight},
+	}
+	if item.Intro {
+		seg.Intro = b.introFor(item.Highlight, item.StartTick)
+	}
+	return seg
+}
+
+// povSwitchFor hands the POV to item's player once the action already in seg
+// is over, if item belongs to a different player.
+func povSwitchFor(seg recordingSegment, item segmentRange) (povSwitch, bool) {
+	slot := item.Highlight.PlayerSlot
+	if slot <= 0 || slot == seg.currentSlot() {
+		return povSwitch{}, false
+	}
+	actionEnd := 0
+	for _, h := range seg.Highlights {
+		actionEnd = max(actionEnd, h.SegmentTo)
+	}
+	at := min(max(item.StartTick, actionEnd+1), max(seg.EndTick, item.EndTick))
+	return povSwitch{AtTick: at, PlayerSlot: slot}, true
+}
+
+// highlightWindows returns the tick windows to record for h. A kill streak
+// spans several rounds, so it is recorded around each of its kills rather
+// than end to end.
+func highlightWindows(h model.Highlight) [][2]int {
+	if h.Type != model.HighlightKillStreak || len(h.KillTicks) == 0 {
+		return [][2]int{{h.SegmentFrom, h.SegmentTo}}
+	}
+	windows := make([][2]int, 0, len(h.KillTicks))
+	for _, tick := range h.KillTicks {
+		windows = append(windows, [2]int{tick, tick})
+	}
+	return windows
+}
+
+func (b *ScriptBuilder) writeSetup(w *strings.Builder, result model.HighlightResult, name string, segs []recordingSegment) {
+	steamID := result.SteamID
 	writeCommandLine(w, "mirv_cvar_unhide_all")
 	writeCommandLine(w, "mirv_cmd clear")
 	writeCommandLine(w, "mirv_streams record end")
-	dir := strings.TrimSpace(b.OutputPath)
-	if dir != "" {
-		parts := []string{dir, sanitizeNameToken(steamID), time.Now().Format("2006-01-02")}
-		if token := sanitizeNameToken(name); token != "" {
-			parts = append(parts, token)
-		}
-		writeCommandLine(w, fmt.Sprintf(`mirv_streams record name "%s"`, path.Join(parts...)))
+	if base := b.recordBase(steamID, name); base != "" {
+		writeCommandLine(w, fmt.Sprintf(`mirv_streams record name "%s"`, base))
+	}
+	writeCommandLine(w, fmt.Sprintf("mirv_streams settings edit afxDefault settings %s", b.screenPreset()))
+	for _, line := range b.layerCommands() {
+		writeCommandLine(w, line)
 	}
-	writeCommandLine(w, fmt.Sprintf("mirv_streams settings edit afxDefault settings %s", b.ffmpegPreset()))
-	writeCommandLine(w, "mirv_streams record screen enabled 1")
 	writeCommandLine(w, fmt.Sprintf("mirv_streams record fps %d", b.frameRate()))
 	writeCommandLine(w, "spec_show_xray 0")
 	writeCommandLine(w, "demoui 0")
 	writeCommandLine(w, "cl_trueview_show_status 0")
-	writeCommandLine(w, "cl_drawhud 0")
-	writeCommandLine(w, "r_show_build_info false")
-	writeCommandLine(w, "cl_drawhud_force_radar -1")
-	writeCommandLine(w, "cl_drawhud_force_deathnotices 1")
-	writeCommandLine(w, "mirv_deathmsg filter clear")
-	if steamID != "" {
-		writeCommandLine(w, fmt.Sprintf("mirv_deathmsg localPlayer x%s", steamID))
-		writeCommandLine(w, fmt.Sprintf("mirv_deathmsg filter add attackerMatch=!x%s block=1 lastRule=1", steamID))
-	}
-	writeCommandLine(w, "toggleconsole")
+	for _, line := range b.hudCommands() {
+		writeCommandLine(w, line)
+	}
+	if b.replaysEnabled() {
+		writeCommandLine(w, fmt.Sprintf(`alias %s ""`, replayNop))
+	}
+	if b.CampathPath != "" && hasIntro(segs) {
+		writeCommandLine(w, "mirv_campath clear")
+		writeCommandLine(w, fmt.Sprintf(`mirv_campath load "%s"`, b.CampathPath))
+		writeCommandLine(w, "mirv_campath enabled 0")
+	}
+	if b.slowMotionEnabled() {
+		writeCommandLine(w, "sv_cheats 1")
+		writeCommandLine(w, "host_timescale 1")
+	}
+	for _, line := range b.deathmsgCommands(result) {
+		writeCommandLine(w, line)
+	}
+	if b.Next == "" {
+		writeCommandLine(w, "toggleconsole")
+	}
 	w.WriteString("\n")
 }
 
-func (b *ScriptBuilder) writeTickCommands(w *strings.Builder, segs []recordingSegment) {
+// recordBase is the mirv_streams record name of a script,
+// <output>/<steamid>/<date>/<target>, or "" when no output path is set.
+func (b *ScriptBuilder) recordBase(steamID string, name string) string {
+	dir := strings.TrimSpace(b.OutputPath)
+	if dir == "" {
+		return ""
+	}
+	date := b.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	parts := []string{dir, sanitizeNameToken(steamID), date.Format("2006-01-02")}
+	if token := sanitizeNameToken(name); token != "" {
+		parts = append(parts, token)
+	}
+	return path.Join(parts...)
+}
+
+func (b *ScriptBuilder) writeTickCommands(w *strings.Builder, segs []recordingSegment, base string, split scriptSplit) []string {
 	if len(segs) == 0 {
 		writeCommandLine(w, "echo \"No highlights found.\"")
-		return
+		return nil
 	}
 
-	for i, seg := range segs {
-		startParts := append(povCommandsBySlot(seg.PlayerSlot),
+	firstIntro := firstIntroIndex(segs)
+	parts := writeSegments(w, len(segs), func(w *strings.Builder, i int, exec string) {
+		seg := segs[i]
+		startParts := append(b.recordNameCommands(base, segmentRecordName(seg)), povCommandsBySlot(seg.PlayerSlot)...)
+		startParts = append(startParts,
 			fmt.Sprintf("host_framerate %d", b.frameRate()),
 			"mirv_streams record start",
 		)
-		startCmd := joinCommands(startParts...)
-		endCmd := joinCommands("mirv_streams record end", "host_framerate 0")
-
-		writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"%s\"", seg.StartTick, startCmd))
-		writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"%s\"", seg.EndTick, endCmd))
+		cmds := []tickCommand{
+			{Tick: seg.StartTick, Body: joinCommands(startParts...)},
+			{Tick: seg.EndTick, Body: b.stopCommand()},
+		}
+		cmds = append(cmds, b.campathCommands(seg, i == firstIntro)...)
+		cmds = append(cmds, povSwitchCommands(seg)...)
+		cmds = append(cmds, b.slowMotionCommands(seg, false)...)
 		for _, jump := range b.resolveIntraSegmentJumps(seg) {
 			jumpParts := append([]string{"demo_pause", fmt.Sprintf("demo_gototick %d", jump.SeekTick)}, povCommandsBySlot(jump.PlayerSlot)...)
 			jumpParts = append(jumpParts, "demo_resume")
-			writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"%s\"", jump.AtTick, joinCommands(jumpParts...)))
+			cmds = append(cmds, tickCommand{Tick: jump.AtTick, Body: joinCommands(jumpParts...)})
 		}
 
+		var after []tickCommand
 		if i+1 < len(segs) {
 			next := segs[i+1]
-			nextSeek := seekTickBefore(next.StartTick)
-			writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"%s\"", seg.EndTick+1, buildSeekJumpCommand(nextSeek, next.PlayerSlot)))
+			after = []tickCommand{{Tick: 1, Body: joinCommands(exec, buildSeekJumpCommand(seekTickBefore(next.StartTick), next.PlayerSlot))}}
+		} else {
+			after = []tickCommand{
+				{Tick: 1, Body: fmt.Sprintf("echo === All %d segments recorded ===", len(segs))},
+				{Tick: 2, Body: b.doneCommand()},
+			}
 		}
-	}
-
-	lastTick := segs[len(segs)-1].EndTick + 1
-	writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"echo === All %d segments recorded ===\"", lastTick, len(segs)))
-	writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"disconnect\"", lastTick+1))
+		b.writeSegmentCommands(w, i, seg, cmds, after, true, base)
+	}, split)
 	w.WriteString("\n")
 
 	b.writeInitialSeek(w, segs[0], "Auto-seek to first segment")
+	return parts
 }
 
-func (b *ScriptBuilder) writeMontageCommands(w *strings.Builder, segs []recordingSegment) {
+func (b *ScriptBuilder) writeMontageCommands(w *strings.Builder, segs []recordingSegment, split scriptSplit) []string {
 	if len(segs) == 0 {
 		writeCommandLine(w, "echo \"No highlights found for montage.\"")
-		return
+		return nil
 	}
 
 	first := segs[0]
+	firstIntro := firstIntroIndex(segs)
+	parts := writeSegments(w, len(segs), func(w *strings.Builder, i int, exec string) {
+		seg := segs[i]
+		var cmds []tickCommand
+		if i == 0 {
+			startParts := append(povCommandsBySlot(first.PlayerSlot),
+				fmt.Sprintf("host_framerate %d", b.frameRate()),
+				"mirv_streams record start",
+			)
+			cmds = append(cmds, tickCommand{Tick: first.StartTick, Body: joinCommands(startParts...)})
+		}
+		cmds = append(cmds, b.campathCommands(seg, i == firstIntro)...)
+		cmds = append(cmds, povSwitchCommands(seg)...)
+		cmds = append(cmds, b.slowMotionCommands(seg, i+1 < len(segs))...)
 
-	startParts := append(povCommandsBySlot(first.PlayerSlot),
-		fmt.Sprintf("host_framerate %d", b.frameRate()),
-		"mirv_streams record start",
-	)
-	writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"%s\"", first.StartTick, joinCommands(startParts...)))
+		var after []tickCommand
+		if i+1 < len(segs) {
+			next := segs[i+1]
+			after = []tickCommand{{Tick: 1, Body: joinCommands(exec, buildSeekJumpCommand(seekTickBefore(next.StartTick), next.PlayerSlot))}}
+		} else {
+			after = []tickCommand{
+				{Tick: 0, Body: b.stopCommand()},
+				{Tick: 1, Body: "echo === Montage recorded ==="},
+				{Tick: 2, Body: b.doneCommand()},
+			}
+		}
+		b.writeSegmentCommands(w, i, seg, cmds, after, false, "")
+	}, split)
+	w.WriteString("\n")
+
+	b.writeInitialSeek(w, first, "Auto-seek to first montage segment")
+	return parts
+}
 
-	for i, seg := range segs {
-		if i+1 >= len(segs) {
+// doneCommand runs once the last segment is recorded.
+func (b *ScriptBuilder) doneCommand() string {
+	return cmp.Or(b.Next, "disconnect")
+}
+
+// stopCommand ends a recording and returns playback to real time.
+func (b *ScriptBuilder) stopCommand() string {
+	parts := []string{"mirv_streams record end", "host_framerate 0"}
+	if b.slowMotionEnabled() {
+		parts = append(parts, "host_timescale 1")
+	}
+	return joinCommands(parts...)
+}
+
+// slowMotionCommands ramps playback down around each kill tick in seg and
+// back up afterwards. Only host_timescale changes: host_framerate stays at
+// the target fps, so every recorded frame covers Scale/fps of game time and
+// the window plays 1/Scale times longer, as the manifest times it. A window
+// that reaches the segment end is restored by the stop command, unless the
+// recording continues (montage).
+func (b *ScriptBuilder) slowMotionCommands(seg recordingSegment, restoreAtEnd bool) []tickCommand {
+	if !b.slowMotionEnabled() {
+		return nil
+	}
+	slow := "host_timescale " + formatFloat(b.SlowMotionScale)
+	var cmds []tickCommand
+	for _, window := range b.slowMotionWindows(seg) {
+		cmds = append(cmds, tickCommand{Tick: window[0], Body: slow})
+		if window[1] < seg.EndTick || restoreAtEnd {
+			cmds = append(cmds, tickCommand{Tick: window[1], Body: b.normalSpeedCommand()})
+		}
+	}
+	return cmds
+}
+
+func (b *ScriptBuilder) normalSpeedCommand() string {
+	return "host_timescale 1"
+}
+
+// slowMotionWindows returns the merged slow-motion windows of seg, one per
+// kill tick (TickStart when a highlight has none), clamped inside the
+// segment so they never collide with its start command.
+func (b *ScriptBuilder) slowMotionWindows(seg recordingSegment) [][2]int {
+	var ticks []int
+	for _, h := range seg.Highlights {
+		if len(h.KillTicks) == 0 {
+			ticks = append(ticks, h.TickStart)
 			continue
 		}
+		ticks = append(ticks, h.KillTicks...)
+	}
+	sort.Ints(ticks)
 
-		next := segs[i+1]
-		nextSeek := seekTickBefore(next.StartTick)
-		writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"%s\"", seg.EndTick+1, buildSeekJumpCommand(nextSeek, next.PlayerSlot)))
+	var windows [][2]int
+	for _, tick := range ticks {
+		start := max(tick-b.SlowMotionTicks, seg.StartTick+1)
+		end := min(tick+b.SlowMotionTicks, seg.EndTick)
+		if start >= end {
+			continue
+		}
+		if n := len(windows); n > 0 && start <= windows[n-1][1] {
+			windows[n-1][1] = max(windows[n-1][1], end)
+			continue
+		}
+		windows = append(windows, [2]int{start, end})
 	}
+	return windows
+}
 
-	last := segs[len(segs)-1]
-	stopCmd := joinCommands("mirv_streams record end", "host_framerate 0")
-	writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"%s\"", last.EndTick, stopCmd))
-	writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"echo === Montage recorded ===\"", last.EndTick+1))
-	writeCommandLine(w, fmt.Sprintf("mirv_cmd addAtTick %d \"disconnect\"", last.EndTick+2))
-	w.WriteString("\n")
+func (b *ScriptBuilder) slowMotionEnabled() bool {
+	return b.SlowMotionScale > 0 && b.SlowMotionScale < 1 && b.SlowMotionTicks > 0
+}
 
-	b.writeInitialSeek(w, first, "Auto-seek to first montage segment")
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func povSwitchCommands(seg recordingSegment) []tickCommand {
+	cmds := make([]tickCommand, 0, len(seg.Switches))
+	for _, sw := range seg.Switches {
+		cmds = append(cmds, tickCommand{Tick: sw.AtTick, Body: joinCommands(povCommandsBySlot(sw.PlayerSlot)...)})
+	}
+	return cmds
 }
 
 func (b *ScriptBuilder) writeMontageFooter(w *strings.Builder, segs []recordingSegment, montageName string) {
@@ -1,13 +1,29 @@
 package hlae
 
 import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
 )
 
+// assertLintClean plays script back in the linter and expects no errors and
+// takes recordings.
+func assertLintClean(t *testing.T, script string, takes int) {
+	t.Helper()
+	report := Lint(script, LintOptions{})
+	if report.Errors() > 0 || len(report.Takes) != takes {
+		t.Fatalf("expected %d takes without lint errors, got %d takes and %v:\n%s", takes, len(report.Takes), report.Issues, script)
+	}
+}
+
 // Date segment is generated at runtime, hence a pattern rather than a fixed string.
 func assertSetupRecordName(t *testing.T, script, output, steamID, name string) {
 	t.Helper()
@@ -131,6 +147,7 @@ func TestBuildClipsAddsAutoSkipBetweenSegments(t *testing.T) {
 	}
 
 	script := builder.BuildClips(result, nil, "highlights")
+	assertLintClean(t, script, 2)
 	if !strings.Contains(script, "mirv_cmd addAtTick 121 \"demo_pause; demo_gototick 299; spec_player 8; demo_resume\";") {
 		t.Fatalf("expected pause->seek->resume with next segment slot")
 	}
@@ -162,6 +179,7 @@ func TestBuildClipsAddsIntraSegmentJumpForRoundMultikillGap(t *testing.T) {
 	}
 
 	script := builder.BuildClips(result, nil, "highlights")
+	assertLintClean(t, script, 1)
 	if !strings.Contains(script, "mirv_cmd addAtTick 136 \"demo_pause; demo_gototick 229; spec_player 7; demo_resume\";") {
 		t.Fatalf("expected one intra-segment jump for large kill gap")
 	}
@@ -187,6 +205,7 @@ func TestBuildMontageSingleOutputFile(t *testing.T) {
 	}
 
 	script := builder.BuildMontage(result, model.Selection{model.HighlightHeadshot: true}, "hs")
+	assertLintClean(t, script, 1)
 	assertSetupRecordName(t, script, "highlights", "76561197960266727", "hs")
 	if strings.Count(script, "mirv_streams record start") != 1 {
 		t.Fatalf("expected exactly one record start for montage")
@@ -225,3 +244,718 @@ func TestHelpersUseStableFallbacks(t *testing.T) {
 		t.Fatalf("expected seek tick 7, got %d", got)
 	}
 }
+
+func TestBuildClipsFiltersKillfeedByVictimForDeaths(t *testing.T) {
+	builder := NewScriptBuilder()
+
+	result := model.HighlightResult{
+		SteamID:     "76561197960266727",
+		Perspective: model.PerspectiveDeaths,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightDeath, Round: 2, PlayerSlot: 4, SegmentFrom: 100, SegmentTo: 100},
+		},
+	}
+
+	script := builder.BuildClips(result, nil, "deaths")
+	if !strings.Contains(script, "mirv_deathmsg filter add victimMatch=!x76561197960266727 block=1 lastRule=1;") {
+		t.Fatalf("expected killfeed filter on the victim for a deaths reel")
+	}
+	if !strings.Contains(script, "spec_player 4;") {
+		t.Fatalf("expected POV lock on the victim slot")
+	}
+}
+
+func TestResolveSegmentsSplitsKillStreakPerKill(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.StartOffsetTicks = 10
+	builder.EndOffsetTicks = 10
+
+	highlights := []model.Highlight{
+		{
+			Type:        model.HighlightKillStreak,
+			PlayerSlot:  5,
+			SegmentFrom: 1000,
+			SegmentTo:   9000,
+			KillTicks:   []int{1000, 1015, 5000, 9000},
+		},
+	}
+
+	segs := builder.resolveSegments(highlights, nil)
+	if len(segs) != 3 {
+		t.Fatalf("expected 3 segments around the streak kills, got %d", len(segs))
+	}
+	if segs[0].StartTick != 990 || segs[0].EndTick != 1025 {
+		t.Fatalf("expected close kills merged into 990..1025, got %d..%d", segs[0].StartTick, segs[0].EndTick)
+	}
+	if segs[2].StartTick != 8990 || segs[2].EndTick != 9010 {
+		t.Fatalf("unexpected last streak segment: %d..%d", segs[2].StartTick, segs[2].EndTick)
+	}
+}
+
+func TestBuildMontageSwitchesPovInsideMergedTeamSegment(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.StartOffsetTicks = 50
+	builder.EndOffsetTicks = 50
+
+	result := model.HighlightResult{
+		Highlights: []model.Highlight{
+			{Type: model.HighlightHeadshot, Round: 1, PlayerSlot: 3, SteamID: "a", SegmentFrom: 1000, SegmentTo: 1000},
+			{Type: model.HighlightWallbang, Round: 1, PlayerSlot: 8, SteamID: "b", SegmentFrom: 1040, SegmentTo: 1040},
+		},
+	}
+
+	script := builder.BuildMontage(result, nil, "top")
+	if !strings.Contains(script, "mirv_cmd addAtTick 950 \"spec_player 3; host_framerate 60; mirv_streams record start\";") {
+		t.Fatalf("expected montage to start on the first player's POV")
+	}
+	if !strings.Contains(script, "mirv_cmd addAtTick 1001 \"spec_player 8\";") {
+		t.Fatalf("expected POV switch to the second player after the first kill, got:\n%s", script)
+	}
+}
+
+func TestBuildClipsRampsSlowMotionAroundKillTicks(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.StartOffsetTicks = 100
+	builder.EndOffsetTicks = 100
+	builder.SlowMotionScale = 0.25
+	builder.SlowMotionTicks = 20
+
+	result := model.HighlightResult{
+		Highlights: []model.Highlight{
+			{Type: model.HighlightMultiKill, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1030, KillTicks: []int{1000, 1030}},
+			{Type: model.HighlightHeadshot, PlayerSlot: 7, SegmentFrom: 1490, SegmentTo: 1490, TickStart: 1490},
+		},
+	}
+
+	script := builder.BuildClips(result, nil, "slowmo")
+	assertLintClean(t, script, 2)
+	if !strings.Contains(script, "sv_cheats 1;") {
+		t.Fatalf("expected sv_cheats for host_timescale in setup")
+	}
+	// Overlapping windows of the two multikill kills merge into one ramp.
+	if !strings.Contains(script, "mirv_cmd addAtTick 980 \"host_timescale 0.25\";") {
+		t.Fatalf("expected slow-motion ramp before first kill:\n%s", script)
+	}
+	if !strings.Contains(script, "mirv_cmd addAtTick 1050 \"host_timescale 1\";") {
+		t.Fatalf("expected normal speed restored after merged window:\n%s", script)
+	}
+	if strings.Count(script, "host_timescale 0.25") != 2 {
+		t.Fatalf("expected one ramp per merged window:\n%s", script)
+	}
+	if !strings.Contains(script, "\"mirv_streams record end; host_framerate 0; host_timescale 1\";") {
+		t.Fatalf("expected stop command to reset host_timescale")
+	}
+}
+
+func TestBuildMontageRestoresSpeedBeforeNextSegment(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.SlowMotionScale = 0.5
+	builder.SlowMotionTicks = 50
+
+	result := model.HighlightResult{
+		Highlights: []model.Highlight{
+			{Type: model.HighlightHeadshot, PlayerSlot: 4, SegmentFrom: 100, SegmentTo: 110, TickStart: 100},
+			{Type: model.HighlightHeadshot, PlayerSlot: 8, SegmentFrom: 300, SegmentTo: 310, TickStart: 300},
+		},
+	}
+
+	script := builder.BuildMontage(result, nil, "hs")
+	assertLintClean(t, script, 1)
+	if !strings.Contains(script, "mirv_cmd addAtTick 101 \"host_timescale 0.5\";") {
+		t.Fatalf("expected window clamped to just after segment start:\n%s", script)
+	}
+	if !strings.Contains(script, "mirv_cmd addAtTick 110 \"host_timescale 1\";") {
+		t.Fatalf("expected speed restored at segment end before the jump:\n%s", script)
+	}
+	if strings.Contains(script, "mirv_cmd addAtTick 310 \"host_timescale 1\";") {
+		t.Fatalf("last segment is restored by the stop command:\n%s", script)
+	}
+}
+
+func TestSlowMotionCommandsMatchManifestDurations(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.StartOffsetTicks = 192
+	builder.EndOffsetTicks = 128
+	builder.SlowMotionScale = 0.3
+	builder.SlowMotionTicks = 64
+
+	result := model.HighlightResult{
+		TickRate: 64,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightMultiKill, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1100, KillTicks: []int{1000, 1100}},
+		},
+	}
+	script := builder.BuildClips(result, nil, "slowmo")
+	manifest := builder.BuildManifest(result, nil, "slowmo", ModeClips)
+
+	// Play the script back: each tick lasts 1/64 s of game time, recorded
+	// in frames of timescale/framerate game seconds shown at the target fps.
+	commands := regexp.MustCompile(`mirv_cmd addAtTick (\d+) "([^"]*)"`).FindAllStringSubmatch(script, -1)
+	type event struct {
+		tick int
+		body string
+	}
+	var events []event
+	for _, match := range commands {
+		tick, _ := strconv.Atoi(match[1])
+		events = append(events, event{tick: tick, body: match[2]})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].tick < events[j].tick })
+	timescale, framerate, recorded := 1.0, 0.0, 0.0
+	recording, last := false, 0
+	for _, e := range events {
+		if recording {
+			recorded += float64(e.tick-last) / result.TickRate * framerate / timescale / float64(builder.frameRate())
+		}
+		last = e.tick
+		for _, command := range strings.Split(e.body, ";") {
+			fields := strings.Fields(command)
+			switch {
+			case len(fields) == 2 && fields[0] == "host_timescale":
+				timescale, _ = strconv.ParseFloat(fields[1], 64)
+			case len(fields) == 2 && fields[0] == "host_framerate":
+				framerate, _ = strconv.ParseFloat(fields[1], 64)
+			case strings.Join(fields, " ") == "mirv_streams record start":
+				recording = true
+			case strings.Join(fields, " ") == "mirv_streams record end":
+				recording = false
+			}
+		}
+	}
+	if len(manifest.Recordings) != 1 {
+		t.Fatalf("expected one clip, got %+v", manifest.Recordings)
+	}
+	if want := manifest.Recordings[0].Duration; math.Abs(recorded-want) > 0.01 {
+		t.Fatalf("script records %.3fs but the manifest expects %.3fs:\n%s", recorded, want, script)
+	}
+}
+
+func TestBuildClipsReplaysKillFromVictimPov(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.StartOffsetTicks = 100
+	builder.EndOffsetTicks = 50
+	builder.ReplayTicks = 64
+
+	result := model.HighlightResult{
+		Highlights: []model.Highlight{
+			{Type: model.HighlightWallbang, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000, ReplaySlots: []int{3}},
+		},
+	}
+
+	script := builder.BuildClips(result, nil, "replay")
+	assertLintClean(t, script, 2)
+	if !strings.Contains(script, `alias hlr_nop "";`) {
+		t.Fatalf("expected no-op alias in setup")
+	}
+	// The killer take is untouched before the replay window.
+	if !strings.Contains(script, "mirv_cmd addAtTick 900 \"spec_player 7; host_framerate 60; mirv_streams record start\";") {
+		t.Fatalf("expected killer POV take:\n%s", script)
+	}
+	if !strings.Contains(script, "demo_pause; demo_gototick 935; spec_player 3; demo_resume\";") {
+		t.Fatalf("expected seek back into the kill from the victim slot:\n%s", script)
+	}
+	if strings.Count(script, "mirv_streams record start") != 2 {
+		t.Fatalf("expected the replay recorded as its own take:\n%s", script)
+	}
+	// Ticks played twice go through aliases; the segment end fires the
+	// replay phase only in the first pass.
+	if !strings.Contains(script, "alias hlr0_2_on \"hlr0_p1\";\nalias hlr0_2 hlr0_2_on;\nmirv_cmd addAtTick 1051 \"hlr0_2\";") {
+		t.Fatalf("expected gated phase switch after the segment:\n%s", script)
+	}
+	if !strings.Contains(script, "alias hlr0_6_on \"disconnect\";\nalias hlr0_6 hlr_nop;") {
+		t.Fatalf("expected disconnect armed only for the replay phase:\n%s", script)
+	}
+}
+
+func TestBuildMontageRunsPhaseSwitchLastOnSharedTick(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.StartOffsetTicks = 100
+	builder.EndOffsetTicks = 10
+	builder.ReplayTicks = 64
+	builder.ReplayTypes = model.Selection{model.HighlightMultiKill: true}
+
+	result := model.HighlightResult{
+		Highlights: []model.Highlight{
+			{Type: model.HighlightMultiKill, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1005, KillTicks: []int{1000, 1005}, ReplaySlots: []int{3, 4}},
+			{Type: model.HighlightHeadshot, PlayerSlot: 7, SegmentFrom: 1005, SegmentTo: 1005, TickStart: 1005, ReplaySlots: []int{4}},
+		},
+	}
+
+	script := builder.BuildMontage(result, nil, "reel")
+	assertLintClean(t, script, 1)
+	if strings.Count(script, "demo_gototick 935; spec_player 3") != 1 || strings.Count(script, "demo_gototick 940; spec_player 4") != 1 {
+		t.Fatalf("expected one replay per kill, deduplicated across highlights:\n%s", script)
+	}
+	// Both replays end at the segment end, so tick 1016 holds the final echo
+	// and both phase switches: switches run last and the later one first, or
+	// the first switch would arm and immediately run the second.
+	echo := strings.Index(script, "mirv_cmd addAtTick 1016 \"hlr0_4\";")
+	toSecond := strings.Index(script, "mirv_cmd addAtTick 1016 \"hlr0_2\";")
+	toFirst := strings.Index(script, "mirv_cmd addAtTick 1016 \"hlr0_1\";")
+	if echo < 0 || toSecond < echo || toFirst < toSecond {
+		t.Fatalf("unexpected command order on tick 1016:\n%s", script)
+	}
+}
+
+func TestBuildTargetWritesCampathIntro(t *testing.T) {
+	scene := &model.KillScene{
+		Killer:      model.Vector{X: 100, Y: 200, Z: 0},
+		Victim:      model.Vector{X: 600, Y: 200, Z: 0},
+		KillerSpawn: &model.Vector{X: -900, Y: 200, Z: 0},
+	}
+	result := model.HighlightResult{
+		TickRate: 64,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightClutchWin, PlayerSlot: 7, SegmentFrom: 6400, SegmentTo: 6600, TickStart: 6400, Scene: scene},
+			{Type: model.HighlightHeadshot, PlayerSlot: 7, SegmentFrom: 6500, SegmentTo: 6500, TickStart: 6500, Scene: scene},
+			{Type: model.HighlightClutchWin, PlayerSlot: 7, SegmentFrom: 12800, SegmentTo: 12900, TickStart: 12800, Scene: scene},
+		},
+	}
+	options := Options{
+		FrameRate:       60,
+		PreRollSeconds:  1,
+		PostRollSeconds: 1,
+		IntroTypes:      model.Selection{model.HighlightClutchWin: true},
+		IntroStyle:      IntroSpawn,
+		IntroSeconds:    2,
+	}
+
+	files, err := BuildTarget(result, options, Target{Mode: ModeMontage, Path: "reel.cfg", Name: "reel"})
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	if len(files) != 3 || files[1].Path != "reel.campath.xml" || files[2].Path != "reel.manifest.json" {
+		t.Fatalf("expected cfg, campath and manifest files, got %+v", files)
+	}
+	script, campath := files[0].Content, files[1].Content
+	if !regexp.MustCompile(`mirv_campath load "[^"]*/reel\.campath\.xml";`).MatchString(script) {
+		t.Fatalf("expected absolute campath load in setup:\n%s", script)
+	}
+	// Pre-roll (64) plus intro (128) ticks before the clutch.
+	if !strings.Contains(script, "mirv_cmd addAtTick 6208 \"mirv_campath edit start; mirv_campath enabled 1\";") {
+		t.Fatalf("expected first intro to align and enable the path:\n%s", script)
+	}
+	if !strings.Contains(script, "mirv_cmd addAtTick 6336 \"mirv_campath enabled 0; spec_player 7\";") {
+		t.Fatalf("expected intro to hand back to the POV:\n%s", script)
+	}
+	if !strings.Contains(script, "mirv_cmd addAtTick 12608 \"mirv_campath enabled 1\";") {
+		t.Fatalf("expected later intros to only enable the path:\n%s", script)
+	}
+	// Two intros of spawnKeys path points plus the final key on the killer.
+	if got := strings.Count(campath, "<p "); got != 2*(spawnKeys+1) {
+		t.Fatalf("expected %d keyframes, got %d:\n%s", 2*(spawnKeys+1), got, campath)
+	}
+	if !strings.Contains(campath, `<p t="99.00" x="100.00" y="200.00" z="64.00" fov="90.00" rx="0.00" ry="0.00" rz="0.00"/>`) {
+		t.Fatalf("expected last key on the killer's eyes facing the victim:\n%s", campath)
+	}
+}
+
+func TestBuildTargetSkipsCampathWithoutIntros(t *testing.T) {
+	result := model.HighlightResult{
+		TickRate:   64,
+		Highlights: []model.Highlight{{Type: model.HighlightHeadshot, PlayerSlot: 7, SegmentFrom: 100, SegmentTo: 100}},
+	}
+	options := Options{IntroTypes: model.Selection{model.HighlightHeadshot: true}, IntroSeconds: 2}
+
+	files, err := BuildTarget(result, options, Target{Mode: ModeClips, Path: "clips.cfg"})
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	if len(files) != 2 || strings.Contains(files[0].Content, "mirv_campath") {
+		t.Fatalf("expected no campath without kill positions, got %+v", files)
+	}
+}
+
+func TestBuildClipsNamesEachSegmentRecording(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.OutputPath = "C:/rec"
+	builder.StartOffsetTicks = 64
+	builder.EndOffsetTicks = 64
+
+	result := model.HighlightResult{
+		SteamID:  "7656",
+		TickRate: 64,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightMultiKill, Round: 3, Kills: 2, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1100, KillTicks: []int{1000, 1100}},
+			{Type: model.HighlightHeadshot, Round: 9, PlayerSlot: 7, SegmentFrom: 9000, SegmentTo: 9000, TickStart: 9000},
+		},
+	}
+
+	script := builder.BuildClips(result, nil, "clips")
+	for _, want := range []string{
+		`"mirv_streams record name C:/rec/7656/\d{4}-\d{2}-\d{2}/clips/r3_round_multikill_2k_1000; spec_player 7;`,
+		`"mirv_streams record name C:/rec/7656/\d{4}-\d{2}-\d{2}/clips/r9_headshot_kill_1k_9000; spec_player 7;`,
+	} {
+		if !regexp.MustCompile(want).MatchString(script) {
+			t.Fatalf("expected %q in script:\n%s", want, script)
+		}
+	}
+
+	builder.OutputPath = "C:/my recordings"
+	if script := builder.BuildClips(result, nil, "clips"); strings.Contains(script, "record name C:") {
+		t.Fatalf("expected no per-segment names for a path that needs quotes:\n%s", script)
+	}
+	manifest := builder.BuildManifest(result, nil, "clips", ModeClips)
+	if len(manifest.Recordings) != 2 || !strings.HasSuffix(manifest.Recordings[1].Folder, "/clips/take0001") {
+		t.Fatalf("expected numbered takes as fallback, got %+v", manifest.Recordings)
+	}
+}
+
+func TestBuildTargetNamesTakesFromScriptsWhenPathNeedsQuotes(t *testing.T) {
+	result := model.HighlightResult{
+		SteamID:  "7656",
+		TickRate: 64,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightHeadshot, Round: 9, PlayerSlot: 7, SegmentFrom: 9000, SegmentTo: 9000, TickStart: 9000},
+		},
+	}
+	options := Options{OutputPath: "C:/Users/First Last/rec", PreRollSeconds: 1}
+
+	files, err := BuildTarget(result, options, Target{Mode: ModeClips, Path: "out/hs.cfg", Name: "hs"})
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	if !strings.Contains(files[0].Content, `"exec hs/names/r9_headshot_kill_1k_9000; spec_player 7;`) {
+		t.Fatalf("expected the take named from its script:\n%s", files[0].Content)
+	}
+	base := regexp.MustCompile(`mirv_streams record name "([^"]*)"`).FindStringSubmatch(files[0].Content)
+	if len(files) != 3 || filepath.ToSlash(files[1].Path) != "out/hs/names/r9_headshot_kill_1k_9000.cfg" {
+		t.Fatalf("expected the script, a record name script and the manifest, got %+v", files)
+	}
+	if want := "mirv_streams record name \"" + base[1] + "/r9_headshot_kill_1k_9000\"\n"; files[1].Content != want {
+		t.Fatalf("expected %q, got %q", want, files[1].Content)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(files[2].Content), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if folder := manifest.Recordings[0].Folder; folder != base[1]+"/r9_headshot_kill_1k_9000/take0000" {
+		t.Fatalf("expected the named folder under the script's record base %q, got %q", base[1], folder)
+	}
+
+	if _, err := BuildTarget(result, options, Target{Mode: ModeClips, Path: "my clips.cfg", Name: "my clips"}); err == nil {
+		t.Fatalf("expected an error for a script name that cannot be exec'd")
+	}
+}
+
+func TestBuildManifestPlacesKillsInMontageTimeline(t *testing.T) {
+	builder := NewScriptBuilder()
+	builder.StartOffsetTicks = 64
+	builder.EndOffsetTicks = 64
+	builder.SlowMotionScale = 0.5
+	builder.SlowMotionTicks = 32
+
+	result := model.HighlightResult{
+		TickRate: 64,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightHeadshot, Round: 1, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000},
+			{Type: model.HighlightWallbang, Round: 4, PlayerSlot: 7, SegmentFrom: 5000, SegmentTo: 5000, TickStart: 5000},
+		},
+	}
+
+	manifest := builder.BuildManifest(result, nil, "reel", ModeMontage)
+	if manifest.Mode != "montage" || len(manifest.Recordings) != 1 {
+		t.Fatalf("expected one montage recording, got %+v", manifest)
+	}
+	recording := manifest.Recordings[0]
+	if recording.Folder != "take0000" {
+		t.Fatalf("expected relative take folder without output path, got %q", recording.Folder)
+	}
+	// Each segment: 0.5s real time, 1s at half speed to the kill, 1s after it, 0.5s.
+	if recording.Duration != 6 || len(recording.Cuts) != 6 {
+		t.Fatalf("expected 6s in 6 cuts, got %v in %+v", recording.Duration, recording.Cuts)
+	}
+	if len(recording.Highlights) != 2 || recording.Highlights[0].PreRoll != 1.5 || recording.Highlights[1].KillTimes[0] != 4.5 {
+		t.Fatalf("expected kills at 1.5s and 4.5s, got %+v", recording.Highlights)
+	}
+}
+
+func TestBuildClipsAppliesHUDPreset(t *testing.T) {
+	result := model.HighlightResult{
+		SteamID:    "76561198000000000",
+		TickRate:   64,
+		Highlights: []model.Highlight{{Type: model.HighlightHeadshot, Round: 2, PlayerSlot: 7, SegmentFrom: 640, SegmentTo: 640, TickStart: 640}},
+	}
+	const block = "mirv_deathmsg filter add attackerMatch=!x76561198000000000 block=1 lastRule=1;"
+
+	builder := NewScriptBuilder()
+	script := builder.BuildClips(result, nil, "clips")
+	for _, want := range []string{"cl_drawhud 0;", "cl_drawhud_force_deathnotices 1;", block} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected %q with the default preset:\n%s", want, script)
+		}
+	}
+
+	builder.HUD = HUD{Preset: HUDHighlight, Lifetime: 8, Color: "ff4040", HideVictims: true}
+	script = builder.BuildClips(result, nil, "clips")
+	want := "mirv_deathmsg lifetime 8;\n" +
+		"mirv_deathmsg localPlayer x76561198000000000;\n" +
+		"mirv_deathmsg filter add attackerMatch=x76561198000000000 attackerColor=ff4040;\n" +
+		"mirv_deathmsg filter add \"victimName= \";\n"
+	if !strings.Contains(script, want) || strings.Contains(script, block) {
+		t.Fatalf("expected every notice kept, styled for the player:\n%s", script)
+	}
+
+	builder.HUD = HUD{Preset: HUDFull}
+	script = builder.BuildClips(result, nil, "clips")
+	if !strings.Contains(script, "cl_drawhud 1;") || !strings.Contains(script, "cl_drawhud_force_radar 0;") {
+		t.Fatalf("expected the full HUD:\n%s", script)
+	}
+
+	builder.HUD = HUD{Preset: HUDClean, Lifetime: 8}
+	script = builder.BuildClips(result, nil, "clips")
+	if !strings.Contains(script, "cl_drawhud_force_deathnotices -1;") || strings.Contains(script, "mirv_deathmsg lifetime") || strings.Contains(script, "localPlayer") {
+		t.Fatalf("expected no death notices:\n%s", script)
+	}
+	assertLintClean(t, script, 1)
+}
+
+func TestBuildTargetRecordsStreamLayers(t *testing.T) {
+	result := model.HighlightResult{
+		TickRate:   64,
+		Highlights: []model.Highlight{{Type: model.HighlightHeadshot, Round: 2, PlayerSlot: 7, SegmentFrom: 640, SegmentTo: 640, TickStart: 640}},
+	}
+	target := Target{Mode: ModeClips, Path: "layers.cfg", Name: "layers", Layers: []StreamLayer{
+		{Name: "world", Kind: LayerWorld},
+		{Name: "deathmsg", Kind: LayerDeathmsg, Preset: "afxFfmpegLosslessBest"},
+		{Name: "depth", Kind: LayerDepth},
+	}}
+
+	files, err := BuildTarget(result, Options{FFmpegPreset: "afxFfmpegYuv420p"}, target)
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	script := files[0].Content
+	for _, want := range []string{
+		"mirv_streams remove world;\nmirv_streams add normal world;\nmirv_streams edit world drawHud 0;\nmirv_streams edit world settings afxFfmpegYuv420p;",
+		"mirv_streams add baseFx deathmsg;\nmirv_streams edit deathmsg drawHud 1;\nmirv_streams edit deathmsg clearBeforeHud black;\nmirv_streams edit deathmsg settings afxFfmpegLosslessBest;",
+		"mirv_streams add depth depth;",
+		"mirv_streams record screen enabled 0;",
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected %q in setup:\n%s", want, script)
+		}
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(files[1].Content), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if len(manifest.Layers) != 3 || manifest.Layers[1].Preset != "afxFfmpegLosslessBest" {
+		t.Fatalf("expected the three layers in the manifest, got %+v", manifest.Layers)
+	}
+	streams := manifest.Recordings[0].Streams
+	if len(streams) != 3 || streams[2] != "take0000/depth" {
+		t.Fatalf("expected a stream folder per layer, got %v", streams)
+	}
+
+	if script := NewScriptBuilder().BuildClips(result, nil, "plain"); !strings.Contains(script, "mirv_streams record screen enabled 1;") {
+		t.Fatalf("expected the screen stream without layers:\n%s", script)
+	}
+
+	builder := NewScriptBuilder()
+	builder.Layers = []StreamLayer{{Name: "world", Kind: LayerWorld, Preset: "x;disconnect"}, {Name: "screen", Kind: LayerScreen, Preset: "; \""}}
+	script = builder.BuildClips(result, nil, "injected")
+	if strings.Contains(script, ";disconnect") || !strings.Contains(script, "mirv_streams edit world settings xdisconnect;") {
+		t.Fatalf("expected the world preset sanitized:\n%s", script)
+	}
+	if !strings.Contains(script, "mirv_streams settings edit afxDefault settings afxFfmpegYuv420p;") {
+		t.Fatalf("expected an unusable screen preset to fall back to the shared one:\n%s", script)
+	}
+}
+
+func TestBuildTargetPrefersTargetOptions(t *testing.T) {
+	result := model.HighlightResult{
+		TickRate:   64,
+		Highlights: []model.Highlight{{Type: model.HighlightHeadshot, Round: 2, PlayerSlot: 7, SegmentFrom: 640, SegmentTo: 640, TickStart: 640}},
+	}
+	shared := Options{FrameRate: 60, PreRollSeconds: 3}
+	target := Target{Mode: ModeMontage, Path: "reel.cfg", Name: "reel", Options: &Options{FrameRate: 240, PreRollSeconds: 1}}
+
+	files, err := BuildTarget(result, shared, target)
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	if !strings.Contains(files[0].Content, "host_framerate 240") || strings.Contains(files[0].Content, "host_framerate 60") {
+		t.Fatalf("expected the target's framerate:\n%s", files[0].Content)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(files[1].Content), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if manifest.FrameRate != 240 || manifest.PreRollSeconds != 1 {
+		t.Fatalf("expected the target's options in the manifest, got fps %d, pre-roll %v", manifest.FrameRate, manifest.PreRollSeconds)
+	}
+}
+
+func TestBuildChainPlaysDemosInTurn(t *testing.T) {
+	highlight := func(tick int) model.Highlight {
+		return model.Highlight{Type: model.HighlightHeadshot, Round: 1, PlayerSlot: 7, SegmentFrom: tick, SegmentTo: tick, TickStart: tick}
+	}
+	results := []model.HighlightResult{
+		{Demo: "demos/mirage.dem", TickRate: 64, Highlights: []model.Highlight{highlight(1000)}},
+		{Demo: "demos/nuke.dem", TickRate: 64},
+		{Demo: "demos/inferno.dem", TickRate: 64, Highlights: []model.Highlight{highlight(2000)}},
+	}
+
+	files, err := BuildChain(results, Options{}, Target{Mode: ModeMontage, Path: "out/reel.cfg", Name: "reel"})
+	if err != nil {
+		t.Fatalf("build chain: %v", err)
+	}
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, filepath.ToSlash(file.Path))
+	}
+	want := []string{"out/reel.cfg", "out/reel/01_mirage.cfg", "out/reel/02_inferno.cfg", "out/reel.manifest.json"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("expected %v, got %v", want, paths)
+	}
+
+	master := files[0].Content
+	for _, line := range []string{
+		`alias hlc1_exec "exec reel/01_mirage";`,
+		`alias hlc1 "mirv_cmd clear; mirv_cmd addAtTick 2 hlc1_exec; playdemo mirage";`,
+		`alias hlc2 "mirv_cmd clear; mirv_cmd addAtTick 2 hlc2_exec; playdemo inferno";`,
+	} {
+		if !strings.Contains(master, line) {
+			t.Fatalf("expected %q in master:\n%s", line, master)
+		}
+	}
+	if !strings.HasSuffix(master, "toggleconsole;\nhlc1;\n") {
+		t.Fatalf("expected master to start the first demo:\n%s", master)
+	}
+
+	first, last := files[1].Content, files[2].Content
+	if !strings.Contains(first, "mirv_cmd addAtTick 1002 \"hlc2\";") || strings.Contains(first, "toggleconsole") {
+		t.Fatalf("expected first part to load the next demo with the console closed:\n%s", first)
+	}
+	if !strings.Contains(last, "mirv_cmd addAtTick 2002 \"disconnect\";") {
+		t.Fatalf("expected last part to disconnect:\n%s", last)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(files[3].Content), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if len(manifest.Recordings) != 2 || manifest.Recordings[1].Demo != "demos/inferno.dem" || manifest.Recordings[1].Folder != "take0001" {
+		t.Fatalf("expected globally numbered takes per demo, got %+v", manifest.Recordings)
+	}
+
+	// With a record path, each demo's script records under its own base, so
+	// HLAE numbers its takes from take0000 again.
+	for mode, suffix := range map[Mode]string{ModeMontage: "take0000", ModeClips: "r1_headshot_kill_1k_"} {
+		files, err := BuildChain(results, Options{OutputPath: "D:/rec"}, Target{Mode: mode, Path: "out/reel.cfg", Name: "reel"})
+		if err != nil {
+			t.Fatalf("build chain: %v", err)
+		}
+		if err := json.Unmarshal([]byte(files[len(files)-1].Content), &manifest); err != nil {
+			t.Fatalf("decode manifest: %v", err)
+		}
+		for k, demo := range []string{"01_mirage", "02_inferno"} {
+			folder := manifest.Recordings[k].Folder
+			base := regexp.MustCompile(`mirv_streams record name "([^"]*)"`).FindStringSubmatch(files[k+1].Content)
+			if !strings.HasPrefix(folder, base[1]+"/") || !strings.HasSuffix(base[1], "/reel_"+demo) || !strings.Contains(folder, "/"+suffix) {
+				t.Fatalf("%s: expected %s's take under its own base %q, got %q", mode, demo, base[1], folder)
+			}
+		}
+		if folder := manifest.Recordings[1].Folder; mode == ModeMontage && !strings.HasSuffix(folder, "/reel_02_inferno/take0000") {
+			t.Fatalf("expected the second demo's montage take at take0000, got %q", folder)
+		}
+	}
+
+	results[0].Demo = "my demo.dem"
+	if _, err := BuildChain(results, Options{}, Target{Mode: ModeMontage, Path: "reel.cfg", Name: "reel"}); err == nil {
+		t.Fatalf("expected error for a demo name playdemo cannot take unquoted")
+	}
+}
+
+func TestBuildTargetSplitsLongScripts(t *testing.T) {
+	result := model.HighlightResult{TickRate: 64}
+	for i := range 12 {
+		tick := 1000 + i*2000
+		result.Highlights = append(result.Highlights, model.Highlight{
+			Type: model.HighlightHeadshot, Round: i + 1, PlayerSlot: 7,
+			SegmentFrom: tick, SegmentTo: tick + 64, TickStart: tick, KillTicks: []int{tick + 32},
+		})
+	}
+	target := Target{Mode: ModeClips, Path: "out/long.cfg", Name: "long"}
+	whole, err := BuildTarget(result, Options{}, target)
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	unsplit := Lint(whole[0].Content, LintOptions{})
+
+	const maxLines = 20
+	files, err := BuildTarget(result, Options{MaxLines: maxLines}, target)
+	if err != nil {
+		t.Fatalf("build split target: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, file := range files[1 : len(files)-1] {
+		path := filepath.ToSlash(file.Path)
+		if !strings.HasPrefix(path, "out/long/part") {
+			t.Fatalf("expected part scripts under out/long, got %s", path)
+		}
+		if lines := strings.Count(file.Content, "\n"); lines > maxLines {
+			t.Fatalf("%s has %d lines, over the limit of %d", path, lines, maxLines)
+		}
+		parts[strings.TrimSuffix(strings.TrimPrefix(path, "out/"), ".cfg")] = file.Content
+	}
+	if len(parts) < 2 {
+		t.Fatalf("expected several parts, got %d", len(parts))
+	}
+	if !strings.Contains(files[0].Content, "\nexec long/part01;\n") {
+		t.Fatalf("expected setup to exec the first part:\n%s", files[0].Content)
+	}
+	if !strings.Contains(parts["long/part01"], "exec long/part02;") {
+		t.Fatalf("expected part01 to exec part02:\n%s", parts["long/part01"])
+	}
+
+	split := Lint(files[0].Content, LintOptions{Exec: func(name string) (string, bool) {
+		content, ok := parts[name]
+		return content, ok
+	}})
+	if split.Errors() > 0 || !reflect.DeepEqual(split.Takes, unsplit.Takes) {
+		t.Fatalf("expected split script to record the same takes, got %+v (%v), want %+v", split.Takes, split.Issues, unsplit.Takes)
+	}
+
+	target.Path = "out/long script.cfg"
+	if _, err := BuildTarget(result, Options{MaxLines: maxLines}, target); err == nil {
+		t.Fatalf("expected error for a script name exec cannot take unquoted")
+	}
+}
+
+func TestLintReportsScriptProblems(t *testing.T) {
+	script := strings.Join([]string{
+		`mirv_cmd clear;`,
+		`mirv_cmd addAtTick 100 "mirv_streams record start";`,
+		`mirv_cmd addAtTick 150 "mirv_streams record start";`,
+		`mirv_cmd addAtTick 200 "demo_gototick 120";`,
+		`mirv_cmd addAtTick 250 "echo "done"";`,
+		`echo "unbalanced;`,
+		`mirv_cmd addAtTick 900 "mirv_streams record end";`,
+		`demo_gototick 50;`,
+	}, "\n")
+
+	report := Lint(script, LintOptions{LastTick: 800})
+	want := []string{
+		"5: error: nested quotes",
+		"6: error: unbalanced quotes",
+		"3: error: record start while already recording",
+		"4: warning: seek back to tick 120",
+		"4: error: playback loops: the seek to tick 120 keeps firing",
+		"error: record start without a matching record end",
+		"7: error: command is after the demo's last tick 800",
+	}
+	if len(report.Issues) != len(want) {
+		t.Fatalf("expected %d issues, got %v", len(want), report.Issues)
+	}
+	for i, issue := range report.Issues {
+		if !strings.Contains(issue.String(), want[i]) {
+			t.Fatalf("issue %d: expected %q, got %q", i, want[i], issue)
+		}
+	}
+	if len(report.Takes) != 1 || !reflect.DeepEqual(report.Takes[0].Spans[:2], [][2]int{{100, 200}, {120, 200}}) {
+		t.Fatalf("expected the seek to split the take, got %+v", report.Takes)
+	}
+}
@@ -12,6 +12,9 @@ const (
 	HighlightNoScope     HighlightType = "noscope"
 	HighlightHeadshot    HighlightType = "headshot_kill"
 	HighlightClutchWin   HighlightType = "clutch_win"
+	HighlightDeath       HighlightType = "death"
+	HighlightKillStreak  HighlightType = "kill_streak"
+	HighlightLowHPKill   HighlightType = "low_hp_kill"
 )
 
 func AllHighlightTypes() []HighlightType {
@@ -21,11 +24,27 @@ func AllHighlightTypes() []HighlightType {
 		HighlightWallbang,
 		HighlightNoScope,
 		HighlightHeadshot,
+		HighlightLowHPKill,
 		HighlightMultiKill,
 		HighlightClutchWin,
+		HighlightKillStreak,
+		HighlightDeath,
 	}
 }
 
+// Perspective selects whose side of a kill is extracted: the player's kills
+// (the default) or the player's deaths, for coaching reels.
+type Perspective string
+
+const (
+	PerspectiveKills  Perspective = "kills"
+	PerspectiveDeaths Perspective = "deaths"
+)
+
+func AllPerspectives() []Perspective {
+	return []Perspective{PerspectiveKills, PerspectiveDeaths}
+}
+
 // Selection is the set of highlight types to keep. An empty selection means
 // "all types enabled", so the zero value is a no-op filter.
 type Selection map[HighlightType]bool
@@ -42,8 +61,12 @@ type KillEvent struct {
 	Time       time.Duration
 	Round      int
 	KillerID   string
+	KillerName string
+	AssisterID string
 	KillerSlot int
 	VictimID   string
+	VictimName string
+	VictimSlot int
 	Weapon     string
 	IsInSmoke  bool
 	IsBlinded  bool
@@ -53,8 +76,42 @@ type KillEvent struct {
 	KillerTeam int
 	RoundWon   bool
 
+	// VictimBlinded is set when the victim was flashed at the time of death.
+	VictimBlinded bool
+	// Traded is set when a teammate of the victim killed the killer shortly
+	// after, in the same round.
+	Traded bool
+
+	// IsOpening marks the first kill of the round.
+	IsOpening bool
+
+	// KillerHealth and KillerArmor are the killer's values at the kill tick;
+	// DamageTaken is the health damage the victim dealt to the killer earlier
+	// in the round.
+	KillerHealth int
+	KillerArmor  int
+	DamageTaken  int
+
 	AlliesAliveBefore  int
 	EnemiesAliveBefore int
+
+	// Scene holds the world positions around the kill, for camera paths.
+	Scene *KillScene
+}
+
+// Vector is a world position in game units.
+type Vector struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// KillScene is where a kill happened: killer and victim positions at the kill
+// tick, and where the killer stood when the round's freeze time ended.
+type KillScene struct {
+	Killer      Vector  `json:"killer"`
+	Victim      Vector  `json:"victim"`
+	KillerSpawn *Vector `json:"killer_spawn,omitempty"`
 }
 
 type Highlight struct {
@@ -67,26 +124,123 @@ type Highlight struct {
 	Kills       int               `json:"kills,omitempty"`
 	KillTicks   []int             `json:"kill_ticks,omitempty"`
 	Meta        map[string]string `json:"meta,omitempty"`
+	Score       float64           `json:"score,omitempty"`
 	Victims     []string          `json:"victims,omitempty"`
+	VictimNames []string          `json:"victim_names,omitempty"`
 	Weapon      string            `json:"weapon,omitempty"`
 	PlayerSlot  int               `json:"player_slot,omitempty"`
 	SteamID     string            `json:"steamid"`
 	Demo        string            `json:"demo"`
 	SegmentFrom int               `json:"segment_tick_start"`
 	SegmentTo   int               `json:"segment_tick_end"`
+
+	// ReplaySlots holds the slot of the other player in each kill, in
+	// kill_ticks order (the single kill for one-kill highlights): the victim
+	// of a kill, or the killer of a death.
+	ReplaySlots []int `json:"replay_slots,omitempty"`
+	// Scene is the first kill's KillScene.
+	Scene *KillScene `json:"scene,omitempty"`
 }
 
+// SchemaVersion is the version of the saved HighlightResult layout. Saved
+// results carry it as schema_version; results saved before it existed
+// have none and read as version 0.
+const SchemaVersion = 1
+
 type HighlightResult struct {
-	Demo       string      `json:"demo"`
-	SteamID    string      `json:"steamid"`
-	TickRate   float64     `json:"tick_rate"`
-	Highlights []Highlight `json:"highlights"`
+	SchemaVersion int          `json:"schema_version,omitempty"`
+	Demo          string       `json:"demo"`
+	SteamID       string       `json:"steamid"`
+	TickRate      float64      `json:"tick_rate"`
+	Map           string       `json:"map,omitempty"`
+	Score         *MatchScore  `json:"score,omitempty"`
+	Perspective   Perspective  `json:"perspective,omitempty"`
+	Highlights    []Highlight  `json:"highlights"`
+	Stats         *PlayerStats `json:"stats,omitempty"`
 }
 
+// MatchScore is the scoreboard at the end of the demo, by the side each team
+// finished on. Side is the side the parsed player finished on ("CT" or "T"),
+// empty when the demo was parsed for every player.
+type MatchScore struct {
+	CT   TeamScore `json:"ct"`
+	T    TeamScore `json:"t"`
+	Side string    `json:"side,omitempty"`
+}
+
+type TeamScore struct {
+	Name  string `json:"name,omitempty"`
+	Score int    `json:"score"`
+}
+
+// PlayerStats is the per-round and whole-match scoreboard of one player.
+type PlayerStats struct {
+	Totals StatsTotals  `json:"totals"`
+	Rounds []RoundStats `json:"rounds"`
+}
+
+type StatsTotals struct {
+	Rounds            int     `json:"rounds"`
+	Kills             int     `json:"kills"`
+	Deaths            int     `json:"deaths"`
+	Assists           int     `json:"assists"`
+	Damage            int     `json:"damage"`
+	Headshots         int     `json:"headshots"`
+	ADR               float64 `json:"adr"`
+	HSPercent         float64 `json:"hs_percent"`
+	KASTPercent       float64 `json:"kast_percent"`
+	OpeningKills      int     `json:"opening_kills"`
+	OpeningDeaths     int     `json:"opening_deaths"`
+	ClutchesWon       int     `json:"clutches_won"`
+	ClutchesAttempted int     `json:"clutches_attempted"`
+}
+
+type RoundStats struct {
+	Round        int    `json:"round"`
+	Kills        int    `json:"kills"`
+	Deaths       int    `json:"deaths"`
+	Assists      int    `json:"assists"`
+	Damage       int    `json:"damage"`
+	Headshots    int    `json:"headshots"`
+	KAST         bool   `json:"kast"`
+	OpeningKill  bool   `json:"opening_kill,omitempty"`
+	OpeningDeath bool   `json:"opening_death,omitempty"`
+	Clutch       string `json:"clutch,omitempty"`
+	ClutchWon    bool   `json:"clutch_won,omitempty"`
+}
+
+// ParsedDemo holds the events of one player: Kills where they are the killer,
+// Deaths where they are the victim, Assists on teammates' kills and Damage
+// dealt to enemies. A parse without a player holds every player's events.
 type ParsedDemo struct {
 	Demo     string
 	TickRate float64
+	Map      string
+	Score    *MatchScore
 	Kills    []KillEvent
+	Deaths   []KillEvent
+	Assists  []KillEvent
+	Damage   []DamageEvent
+	Rounds   []RoundInfo
+}
+
+// DamageEvent is health damage dealt to an enemy, capped at the health the
+// victim had left.
+type DamageEvent struct {
+	Tick       int
+	Round      int
+	AttackerID string
+	VictimID   string
+	Damage     int
+}
+
+// RoundInfo describes how a round ended. ClutchEnemies is set when the parsed
+// player was left alone against that many enemies.
+type RoundInfo struct {
+	Number        int
+	Winner        string
+	ClutchEnemies int
+	ClutchWon     bool
 }
 
 type Player struct {
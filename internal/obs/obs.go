@@ -0,0 +1,262 @@
+// Package obs records highlights with OBS Studio: a plan of recording
+// requests timed against demo playback, and a driver that sends them over
+// obs-websocket v5.
+package obs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// OBS request types a plan uses.
+const (
+	StartRecord  = "StartRecord"
+	StopRecord   = "StopRecord"
+	PauseRecord  = "PauseRecord"
+	ResumeRecord = "ResumeRecord"
+)
+
+// DefaultURL is where obs-websocket listens unless configured otherwise.
+const DefaultURL = "ws://127.0.0.1:4455"
+
+// Plan is a recording of one render target, timed from the moment demo
+// playback resumes at StartTick. The demo-control cfg leaves the demo
+// paused there and binds Key to resume it.
+type Plan struct {
+	Demo      string  `json:"demo"`
+	Target    string  `json:"target"`
+	Mode      string  `json:"mode"`
+	TickRate  float64 `json:"tick_rate"`
+	StartTick int     `json:"start_tick"`
+	Key       string  `json:"key"`
+	Steps     []Step  `json:"steps"`
+}
+
+// Step is one request, sent At seconds of playback after the start. Segment
+// is the 0-based segment it starts or ends.
+type Step struct {
+	At      float64 `json:"at"`
+	Request string  `json:"request"`
+	Segment int     `json:"segment"`
+}
+
+// Driver plays a plan against OBS. Wait sleeps for a duration and Now reads
+// the clock (time-based by default, replaced in tests); Logf reports
+// progress and may be nil.
+type Driver struct {
+	URL       string
+	Password  string
+	Countdown time.Duration
+	Wait      func(ctx context.Context, d time.Duration) error
+	Now       func() time.Time
+	Logf      func(format string, args ...any)
+}
+
+func NewDriver() *Driver {
+	return &Driver{
+		URL:       DefaultURL,
+		Countdown: 5 * time.Second,
+		Wait:      sleep,
+		Now:       time.Now,
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// message is an obs-websocket v5 message: an op code and its data.
+type message struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+}
+
+const (
+	opHello           = 0
+	opIdentify        = 1
+	opIdentified      = 2
+	opRequest         = 6
+	opRequestResponse = 7
+)
+
+type hello struct {
+	RPCVersion     int `json:"rpcVersion"`
+	Authentication *struct {
+		Challenge string `json:"challenge"`
+		Salt      string `json:"salt"`
+	} `json:"authentication"`
+}
+
+type identify struct {
+	RPCVersion         int    `json:"rpcVersion"`
+	Authentication     string `json:"authentication,omitempty"`
+	EventSubscriptions int    `json:"eventSubscriptions"`
+}
+
+type request struct {
+	RequestType string `json:"requestType"`
+	RequestID   string `json:"requestId"`
+}
+
+type requestResponse struct {
+	RequestType   string `json:"requestType"`
+	RequestID     string `json:"requestId"`
+	RequestStatus struct {
+		Result  bool   `json:"result"`
+		Code    int    `json:"code"`
+		Comment string `json:"comment"`
+	} `json:"requestStatus"`
+}
+
+// Run connects to OBS, counts down for the editor to press the plan's key
+// in the game, and sends each step at its time after the countdown ends, so
+// slow requests do not push later ones behind the demo. A failed request
+// stops the run, after stopping a recording it left running.
+func (d *Driver) Run(ctx context.Context, plan Plan) error {
+	conn, err := d.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.conn.Close() })
+	defer stop()
+
+	for left := d.Countdown; left > 0; left -= time.Second {
+		d.logf("press %s in the game in %d...", plan.Key, int((left+time.Second-1)/time.Second))
+		if err := d.Wait(ctx, min(time.Second, left)); err != nil {
+			return err
+		}
+	}
+	d.logf("press %s now", plan.Key)
+
+	recording := false
+	start := d.Now()
+	for i, step := range plan.Steps {
+		at := time.Duration(step.At * float64(time.Second))
+		if wait := at - d.Now().Sub(start); wait > 0 {
+			if err := d.Wait(ctx, wait); err != nil {
+				return err
+			}
+		}
+		if err := d.send(conn, step.Request, i+1); err != nil {
+			if recording && step.Request != StopRecord {
+				_ = d.send(conn, StopRecord, len(plan.Steps)+1)
+			}
+			return fmt.Errorf("segment %d: %w", step.Segment+1, err)
+		}
+		recording = step.Request != StopRecord
+		d.logf("%7.2fs  %s (segment %d)", step.At, step.Request, step.Segment+1)
+	}
+	return nil
+}
+
+// connect opens the websocket and identifies, answering the password
+// challenge when OBS sets one.
+func (d *Driver) connect(ctx context.Context) (*wsConn, error) {
+	conn, err := dialWebSocket(ctx, d.URL)
+	if err != nil {
+		return nil, fmt.Errorf("connect to OBS: %w", err)
+	}
+	var greeting hello
+	if err := readMessage(conn, opHello, &greeting); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply := identify{RPCVersion: 1}
+	if auth := greeting.Authentication; auth != nil {
+		if d.Password == "" {
+			conn.Close()
+			return nil, fmt.Errorf("OBS at %s needs a password", d.URL)
+		}
+		reply.Authentication = authResponse(d.Password, auth.Salt, auth.Challenge)
+	}
+	if err := writeMessage(conn, opIdentify, reply); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := readMessage(conn, opIdentified, nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("identify with OBS (wrong password?): %w", err)
+	}
+	return conn, nil
+}
+
+// authResponse answers the obs-websocket challenge:
+// base64(sha256(base64(sha256(password + salt)) + challenge)).
+func authResponse(password, salt, challenge string) string {
+	secret := sha256.Sum256([]byte(password + salt))
+	response := sha256.Sum256([]byte(base64.StdEncoding.EncodeToString(secret[:]) + challenge))
+	return base64.StdEncoding.EncodeToString(response[:])
+}
+
+func (d *Driver) send(conn *wsConn, requestType string, id int) error {
+	requestID := strconv.Itoa(id)
+	if err := writeMessage(conn, opRequest, request{RequestType: requestType, RequestID: requestID}); err != nil {
+		return err
+	}
+	for {
+		var response requestResponse
+		if err := readMessage(conn, opRequestResponse, &response); err != nil {
+			return err
+		}
+		if response.RequestID != requestID {
+			continue
+		}
+		if !response.RequestStatus.Result {
+			return fmt.Errorf("OBS refused %s: %s (code %d)", requestType, response.RequestStatus.Comment, response.RequestStatus.Code)
+		}
+		return nil
+	}
+}
+
+func (d *Driver) logf(format string, args ...any) {
+	if d.Logf != nil {
+		d.Logf(format, args...)
+	}
+}
+
+func writeMessage(conn *wsConn, op int, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(message{Op: op, Data: raw})
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(payload)
+}
+
+// readMessage reads messages until one with op arrives, skipping events, and
+// decodes its data into v (when non-nil).
+func readMessage(conn *wsConn, op int, v any) error {
+	for {
+		payload, err := conn.ReadText()
+		if err != nil {
+			return err
+		}
+		var msg message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("decode OBS message: %w", err)
+		}
+		if msg.Op != op {
+			continue
+		}
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(msg.Data, v)
+	}
+}
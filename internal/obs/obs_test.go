@@ -0,0 +1,162 @@
+package obs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOBS is an obs-websocket v5 server that asks for a password and
+// records the requests it gets. It refuses the request types in fail.
+type fakeOBS struct {
+	password string
+	fail     map[string]bool
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeOBS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || r.Header.Get("Sec-WebSocket-Protocol") != wsProtocol {
+		http.Error(w, "expected an obswebsocket.json upgrade", http.StatusBadRequest)
+		return
+	}
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer netConn.Close()
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n")
+	rw.WriteString("Sec-WebSocket-Protocol: " + wsProtocol + "\r\n\r\n")
+	rw.Flush()
+	conn := &wsConn{conn: netConn, reader: rw.Reader}
+
+	const salt, challenge = "c2FsdA==", "Y2hhbGxlbmdl"
+	writeMessage(conn, opHello, map[string]any{
+		"obsWebSocketVersion": "5.5.0",
+		"rpcVersion":          1,
+		"authentication":      map[string]string{"challenge": challenge, "salt": salt},
+	})
+	var id identify
+	if err := readMessage(conn, opIdentify, &id); err != nil || id.Authentication != authResponse(f.password, salt, challenge) {
+		conn.Close()
+		return
+	}
+	writeMessage(conn, opIdentified, map[string]int{"negotiatedRpcVersion": 1})
+	// An event the driver did not subscribe to, to be skipped.
+	writeMessage(conn, 5, map[string]string{"eventType": "StudioModeStateChanged"})
+
+	for {
+		var req request
+		if err := readMessage(conn, opRequest, &req); err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req.RequestType)
+		f.mu.Unlock()
+		var resp requestResponse
+		resp.RequestType, resp.RequestID = req.RequestType, req.RequestID
+		resp.RequestStatus.Result = !f.fail[req.RequestType]
+		if !resp.RequestStatus.Result {
+			resp.RequestStatus.Code, resp.RequestStatus.Comment = 500, "output is not active"
+		}
+		writeMessage(conn, opRequestResponse, resp)
+	}
+}
+
+func (f *fakeOBS) seen() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+func testDriver(url, password string, waited *[]time.Duration) *Driver {
+	driver := NewDriver()
+	driver.URL = "ws" + strings.TrimPrefix(url, "http")
+	driver.Password = password
+	driver.Countdown = 2 * time.Second
+	var clock time.Time
+	driver.Wait = func(_ context.Context, d time.Duration) error {
+		*waited = append(*waited, d)
+		clock = clock.Add(d)
+		return nil
+	}
+	driver.Now = func() time.Time { return clock }
+	return driver
+}
+
+func TestDriverSendsPlanToOBS(t *testing.T) {
+	fake := &fakeOBS{password: "hunter2"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	plan := Plan{Key: "F8", Steps: []Step{
+		{At: 0, Request: StartRecord, Segment: 0},
+		{At: 2.5, Request: PauseRecord, Segment: 0},
+		{At: 10, Request: ResumeRecord, Segment: 1},
+		{At: 12.25, Request: StopRecord, Segment: 1},
+	}}
+	var waited []time.Duration
+	if err := testDriver(server.URL, "hunter2", &waited).Run(context.Background(), plan); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := []string{StartRecord, PauseRecord, ResumeRecord, StopRecord}
+	if got := fake.seen(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected requests %v, got %v", want, got)
+	}
+	wantWaits := []time.Duration{time.Second, time.Second, 2500 * time.Millisecond, 7500 * time.Millisecond, 2250 * time.Millisecond}
+	if !reflect.DeepEqual(waited, wantWaits) {
+		t.Fatalf("expected countdown then step waits %v, got %v", wantWaits, waited)
+	}
+
+	// Each request takes 100ms to answer; the steps still keep their times
+	// from the start.
+	waited = nil
+	driver := testDriver(server.URL, "hunter2", &waited)
+	var clock time.Time
+	driver.Wait = func(_ context.Context, d time.Duration) error {
+		waited = append(waited, d)
+		clock = clock.Add(d)
+		return nil
+	}
+	driver.Logf = func(string, ...any) { clock = clock.Add(100 * time.Millisecond) }
+	driver.Now = func() time.Time { return clock }
+	if err := driver.Run(context.Background(), plan); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	wantWaits = []time.Duration{time.Second, time.Second, 2400 * time.Millisecond, 7400 * time.Millisecond, 2150 * time.Millisecond}
+	if !reflect.DeepEqual(waited, wantWaits) {
+		t.Fatalf("expected waits against the start %v, got %v", wantWaits, waited)
+	}
+
+	if err := testDriver(server.URL, "wrong", &waited).Run(context.Background(), plan); err == nil {
+		t.Fatalf("expected a wrong password to fail")
+	}
+}
+
+func TestDriverStopsRecordingWhenARequestFails(t *testing.T) {
+	fake := &fakeOBS{password: "hunter2", fail: map[string]bool{PauseRecord: true}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	plan := Plan{Key: "F8", Steps: []Step{
+		{At: 0, Request: StartRecord, Segment: 0},
+		{At: 2, Request: PauseRecord, Segment: 0},
+		{At: 5, Request: ResumeRecord, Segment: 1},
+	}}
+	var waited []time.Duration
+	err := testDriver(server.URL, "hunter2", &waited).Run(context.Background(), plan)
+	if err == nil || !strings.Contains(err.Error(), "segment 1: OBS refused PauseRecord: output is not active") {
+		t.Fatalf("expected the refused request to stop the run, got %v", err)
+	}
+	if got, want := fake.seen(), []string{StartRecord, PauseRecord, StopRecord}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the driver to stop the recording it started, got %v", got)
+	}
+}
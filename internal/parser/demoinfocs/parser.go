@@ -14,6 +14,7 @@ import (
 	demoparser "github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs"
 	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/common"
 	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/events"
+	"github.com/markus-wa/demoinfocs-golang/v5/pkg/demoinfocs/msg"
 
 	"github.com/eSheikh/cs2-demo-highlighter/internal/demo"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
@@ -21,6 +22,10 @@ import (
 
 const progressThrottle = 150 * time.Millisecond
 
+// tradeWindow is how long after a death a teammate may kill the killer for the
+// death to count as traded.
+const tradeWindow = 5 * time.Second
+
 // countingReader tracks bytes read so parse progress can be derived from the
 // file position. CS2 demo headers do not carry a frame count, so the parser's
 // own Progress() stays at 0 — byte position is the reliable signal.
@@ -41,9 +46,9 @@ func NewParser() *Parser {
 	return &Parser{}
 }
 
-// Parse extracts kills for steamID from the demo. onProgress, if non-nil, is
-// called with a 0..1 fraction as parsing advances; it runs on the parsing
-// goroutine, so it must not block.
+// Parse extracts kills and deaths for steamID from the demo; an empty steamID
+// keeps every player's. onProgress, if non-nil, is called with a 0..1 fraction
+// as parsing advances; it runs on the parsing goroutine, so it must not block.
 func (p *Parser) Parse(ctx context.Context, demoPath string, steamID string, onProgress func(float64)) (result model.ParsedDemo, err error) {
 	if err := demo.ValidatePath(demoPath); err != nil {
 		return model.ParsedDemo{}, err
@@ -65,7 +70,8 @@ func (p *Parser) Parse(ctx context.Context, demoPath string, steamID string, onP
 
 	result = newParsedDemo(demoPath)
 	roundWinners := make(map[int]common.Team)
-	registerHandlers(parser, steamID, &result, roundWinners)
+	clutches := make(map[int]clutchStart)
+	registerHandlers(parser, steamID, &result, roundWinners, clutches)
 	registerProgress(parser, func() float64 { return readFraction(counter.read, size) }, onProgress)
 
 	if err = parseDemo(ctx, parser); err != nil {
@@ -75,6 +81,9 @@ func (p *Parser) Parse(ctx context.Context, demoPath string, steamID string, onP
 		result.TickRate = parser.TickRate()
 	}
 	applyRoundWinners(result.Kills, roundWinners)
+	applyRoundWinners(result.Deaths, roundWinners)
+	result.Rounds = buildRounds(roundWinners, clutches)
+	result.Score = finalScore(parser.GameState(), steamID)
 	if onProgress != nil {
 		onProgress(1)
 	}
@@ -190,6 +199,26 @@ func teamSide(team common.Team) string {
 	}
 }
 
+// finalScore reads the scoreboard the demo ends on, and the side steamID
+// finished on. It is nil when neither team scored.
+func finalScore(state demoparser.GameState, steamID string) *model.MatchScore {
+	ct, t := state.TeamCounterTerrorists(), state.TeamTerrorists()
+	if ct == nil || t == nil || ct.Score()+t.Score() == 0 {
+		return nil
+	}
+	score := &model.MatchScore{
+		CT: model.TeamScore{Name: ct.ClanName(), Score: ct.Score()},
+		T:  model.TeamScore{Name: t.ClanName(), Score: t.Score()},
+	}
+	for _, player := range state.Participants().All() {
+		if player != nil && steamID != "" && steamIDFromUint64(player.SteamID64) == steamID {
+			score.Side = teamSide(player.Team)
+			break
+		}
+	}
+	return score
+}
+
 func sortedPlayers(seen map[uint64]model.Player) []model.Player {
 	players := make([]model.Player, 0, len(seen))
 	for _, p := range seen {
@@ -209,45 +238,150 @@ func newParsedDemo(demoPath string) model.ParsedDemo {
 		Demo:     filepath.Base(demoPath),
 		TickRate: 0,
 		Kills:    make([]model.KillEvent, 0),
+		Deaths:   make([]model.KillEvent, 0),
+		Assists:  make([]model.KillEvent, 0),
+		Damage:   make([]model.DamageEvent, 0),
 	}
 }
 
+type duelKey struct {
+	victim   uint64
+	attacker uint64
+}
+
+// clutchStart records the moment the parsed player became the last one alive
+// on their team.
+type clutchStart struct {
+	team    common.Team
+	enemies int
+}
+
 func registerHandlers(
 	parser demoparser.Parser,
 	steamID string,
 	result *model.ParsedDemo,
 	roundWinners map[int]common.Team,
+	clutches map[int]clutchStart,
 ) {
 	parser.RegisterEventHandler(func(e events.TickRateInfoAvailable) {
 		result.TickRate = e.TickRate
 	})
+	parser.RegisterNetMessageHandler(func(m *msg.CSVCMsg_ServerInfo) {
+		result.Map = m.GetMapName()
+	})
+
+	// duelDamage accumulates health damage per (victim, attacker) pair within
+	// the round, so a kill can report what the victim dealt to the killer.
+	duelDamage := make(map[duelKey]int)
 
 	// TotalRoundsPlayed() is already incremented by the time RoundEnd fires, so
 	// snapshot it at RoundStart to key kills and the winner under the same round.
 	// +1 makes the stored round human-facing (round 1 instead of 0).
 	currentRound := 0
+	openingTaken := false
 	parser.RegisterEventHandler(func(e events.RoundStart) {
 		currentRound = parser.GameState().TotalRoundsPlayed() + 1
+		openingTaken = false
+		clear(duelDamage)
+	})
+
+	parser.RegisterEventHandler(func(e events.PlayerHurt) {
+		if e.Player == nil || e.Attacker == nil || e.Player == e.Attacker {
+			return
+		}
+		duelDamage[duelKey{victim: e.Player.SteamID64, attacker: e.Attacker.SteamID64}] += e.HealthDamageTaken
+
+		if parser.GameState().IsWarmupPeriod() || e.Player.Team == e.Attacker.Team {
+			return
+		}
+		attackerID := steamIDFromUint64(e.Attacker.SteamID64)
+		if steamID != "" && attackerID != steamID {
+			return
+		}
+		result.Damage = append(result.Damage, model.DamageEvent{
+			Tick:       parser.GameState().IngameTick(),
+			Round:      currentRound,
+			AttackerID: attackerID,
+			VictimID:   steamIDFromUint64(e.Player.SteamID64),
+			Damage:     e.HealthDamageTaken,
+		})
 	})
 
 	parser.RegisterEventHandler(func(e events.RoundEnd) {
 		roundWinners[currentRound] = e.Winner
 	})
 
+	// spawns keeps where every player stood when freeze time ended, the start
+	// of a spawn-to-kill camera path.
+	spawns := make(map[uint64]model.Vector)
+	parser.RegisterEventHandler(func(events.RoundFreezetimeEnd) {
+		clear(spawns)
+		for _, player := range parser.GameState().Participants().Playing() {
+			if player != nil {
+				spawns[player.SteamID64] = playerPosition(player)
+			}
+		}
+	})
+
 	parser.RegisterEventHandler(func(e events.Kill) {
 		if parser.GameState().IsWarmupPeriod() {
 			return
 		}
 
-		kill, ok := buildKillEvent(parser, steamID, currentRound, e)
+		kill, ok := buildKillEvent(parser, currentRound, e)
 		if !ok {
 			return
 		}
-		result.Kills = append(result.Kills, kill)
+		kill.DamageTaken = duelDamage[duelKey{victim: e.Killer.SteamID64, attacker: e.Victim.SteamID64}]
+		kill.Scene = &model.KillScene{Killer: playerPosition(e.Killer), Victim: playerPosition(e.Victim)}
+		if spawn, ok := spawns[e.Killer.SteamID64]; ok {
+			kill.Scene.KillerSpawn = &spawn
+		}
+		kill.IsOpening = !openingTaken
+		openingTaken = true
+		markTradedDeaths(result.Deaths, kill)
+		if kill.AssisterID != "" && (steamID == "" || kill.AssisterID == steamID) {
+			result.Assists = append(result.Assists, kill)
+		}
+		if steamID == "" || kill.KillerID == steamID {
+			result.Kills = append(result.Kills, kill)
+		}
+		if steamID == "" || kill.VictimID == steamID {
+			result.Deaths = append(result.Deaths, kill)
+		}
+		if _, started := clutches[currentRound]; !started && steamID != "" {
+			if clutch, ok := detectClutchStart(parser.GameState().Participants(), steamID, e.Victim); ok {
+				clutches[currentRound] = clutch
+			}
+		}
 	})
 }
 
-func buildKillEvent(parser demoparser.Parser, steamID string, round int, e events.Kill) (model.KillEvent, bool) {
+// detectClutchStart reports whether victim's death left the player with
+// steamID as the last one alive on their team, facing at least one enemy.
+func detectClutchStart(participants demoparser.Participants, steamID string, victim *common.Player) (clutchStart, bool) {
+	var survivor *common.Player
+	for _, player := range participants.TeamMembers(victim.Team) {
+		if player == nil || player == victim || !player.IsAlive() {
+			continue
+		}
+		if survivor != nil {
+			return clutchStart{}, false
+		}
+		survivor = player
+	}
+	if survivor == nil || steamIDFromUint64(survivor.SteamID64) != steamID {
+		return clutchStart{}, false
+	}
+
+	enemies := countAlivePlayers(participants.TeamMembers(opponentTeam(victim.Team)))
+	if enemies == 0 {
+		return clutchStart{}, false
+	}
+	return clutchStart{team: victim.Team, enemies: enemies}, true
+}
+
+func buildKillEvent(parser demoparser.Parser, round int, e events.Kill) (model.KillEvent, bool) {
 	if e.Killer == nil || e.Victim == nil {
 		return model.KillEvent{}, false
 	}
@@ -257,14 +391,15 @@ func buildKillEvent(parser demoparser.Parser, steamID string, round int, e event
 	if e.Killer.Team == e.Victim.Team {
 		return model.KillEvent{}, false
 	}
-	if steamIDFromUint64(e.Killer.SteamID64) != steamID {
-		return model.KillEvent{}, false
-	}
 
 	weaponName := ""
 	if e.Weapon != nil {
 		weaponName = e.Weapon.String()
 	}
+	assisterID := ""
+	if e.Assister != nil && e.Assister.Team == e.Killer.Team {
+		assisterID = steamIDFromUint64(e.Assister.SteamID64)
+	}
 
 	killerTeam := e.Killer.Team
 	alliesAlive, enemiesAlive := aliveCountsBeforeKill(parser.GameState().Participants(), killerTeam)
@@ -274,8 +409,12 @@ func buildKillEvent(parser demoparser.Parser, steamID string, round int, e event
 		Time:       parser.CurrentTime(),
 		Round:      round,
 		KillerID:   steamIDFromUint64(e.Killer.SteamID64),
-		KillerSlot: killerSlotFromPlayer(e.Killer),
+		KillerName: e.Killer.Name,
+		AssisterID: assisterID,
+		KillerSlot: slotFromPlayer(e.Killer),
 		VictimID:   steamIDFromUint64(e.Victim.SteamID64),
+		VictimName: e.Victim.Name,
+		VictimSlot: slotFromPlayer(e.Victim),
 		Weapon:     weaponName,
 		IsInSmoke:  e.ThroughSmoke,
 		IsBlinded:  e.AttackerBlind,
@@ -284,11 +423,20 @@ func buildKillEvent(parser demoparser.Parser, steamID string, round int, e event
 		IsHeadshot: e.IsHeadshot,
 		KillerTeam: int(killerTeam),
 
+		VictimBlinded: e.Victim.IsBlinded(),
+		KillerHealth:  e.Killer.Health(),
+		KillerArmor:   e.Killer.Armor(),
+
 		AlliesAliveBefore:  alliesAlive,
 		EnemiesAliveBefore: enemiesAlive,
 	}, true
 }
 
+func playerPosition(player *common.Player) model.Vector {
+	position := player.Position()
+	return model.Vector{X: position.X, Y: position.Y, Z: position.Z}
+}
+
 func parseDemo(ctx context.Context, parser demoparser.Parser) (err error) {
 	if parser == nil {
 		return errors.New("demo parser is nil")
@@ -327,7 +475,7 @@ func steamIDFromUint64(id uint64) string {
 	return strconv.FormatUint(id, 10)
 }
 
-func killerSlotFromPlayer(p *common.Player) int {
+func slotFromPlayer(p *common.Player) int {
 	if p == nil {
 		return 0
 	}
@@ -343,6 +491,21 @@ func killerSlotFromPlayer(p *common.Player) int {
 	}
 }
 
+// markTradedDeaths flags the deaths that kill avenges: same round, the dead
+// killer is the one who got the death, and it happened within tradeWindow.
+func markTradedDeaths(deaths []model.KillEvent, kill model.KillEvent) {
+	for i := range deaths {
+		death := &deaths[i]
+		if death.Traded || death.Round != kill.Round || death.KillerID != kill.VictimID {
+			continue
+		}
+		if death.KillerTeam == kill.KillerTeam || kill.Time-death.Time > tradeWindow {
+			continue
+		}
+		death.Traded = true
+	}
+}
+
 func applyRoundWinners(kills []model.KillEvent, roundWinners map[int]common.Team) {
 	for i := range kills {
 		winner, exists := roundWinners[kills[i].Round]
@@ -353,6 +516,23 @@ func applyRoundWinners(kills []model.KillEvent, roundWinners map[int]common.Team
 	}
 }
 
+func buildRounds(roundWinners map[int]common.Team, clutches map[int]clutchStart) []model.RoundInfo {
+	rounds := make([]model.RoundInfo, 0, len(roundWinners))
+	for number, winner := range roundWinners {
+		if number <= 0 {
+			continue
+		}
+		round := model.RoundInfo{Number: number, Winner: teamSide(winner)}
+		if clutch, ok := clutches[number]; ok {
+			round.ClutchEnemies = clutch.enemies
+			round.ClutchWon = clutch.team == winner
+		}
+		rounds = append(rounds, round)
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i].Number < rounds[j].Number })
+	return rounds
+}
+
 func aliveCountsBeforeKill(participants demoparser.Participants, killerTeam common.Team) (allies int, enemies int) {
 	allies = countAlivePlayers(participants.TeamMembers(killerTeam))
 	enemies = countAlivePlayers(participants.TeamMembers(opponentTeam(killerTeam)))
@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/eSheikh/cs2-demo-highlighter/internal/demo"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
 )
 
 func TestParseRejectsNonDemoExtension(t *testing.T) {
@@ -67,3 +69,22 @@ func TestParseHonorsCanceledContext(t *testing.T) {
 		t.Fatalf("expected context.Canceled, got %v", err)
 	}
 }
+
+func TestMarkTradedDeathsWithinWindow(t *testing.T) {
+	t.Parallel()
+
+	deaths := []model.KillEvent{
+		{Round: 3, Time: 10 * time.Second, KillerID: "enemy", KillerTeam: 2, VictimID: "me"},
+		{Round: 5, Time: 50 * time.Second, KillerID: "enemy", KillerTeam: 2, VictimID: "me"},
+	}
+
+	markTradedDeaths(deaths, model.KillEvent{Round: 3, Time: 13 * time.Second, KillerTeam: 3, VictimID: "enemy"})
+	markTradedDeaths(deaths, model.KillEvent{Round: 5, Time: 60 * time.Second, KillerTeam: 3, VictimID: "enemy"})
+
+	if !deaths[0].Traded {
+		t.Fatalf("expected death within trade window to be traded")
+	}
+	if deaths[1].Traded {
+		t.Fatalf("expected late revenge kill not to count as a trade")
+	}
+}
@@ -0,0 +1,116 @@
+package publish
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// Description is the Markdown summary of a target: a section per demo with
+// its map, score and the player's stats, the highlights in the video by type,
+// and the chapter list.
+func (w *Writer) Description(manifest hlae.Manifest, results []model.HighlightResult, chapters []Chapter) string {
+	var sb strings.Builder
+	for _, result := range results {
+		writeMatch(&sb, result)
+	}
+
+	sb.WriteString("## Highlights\n\n| Type | Count | Rounds |\n| --- | ---: | --- |\n")
+	counts := highlightCounts(manifest)
+	for _, typ := range model.AllHighlightTypes() {
+		if rounds, ok := counts[typ]; ok {
+			fmt.Fprintf(&sb, "| %s | %d | %s |\n", typ, len(rounds), joinRounds(rounds))
+		}
+	}
+
+	if len(chapters) > 0 {
+		sb.WriteString("\n## Chapters\n\n")
+		sb.WriteString(ChapterList(chapters))
+	}
+	return sb.String()
+}
+
+func writeMatch(sb *strings.Builder, result model.HighlightResult) {
+	demo := filepath.Base(strings.ReplaceAll(result.Demo, `\`, "/"))
+	title := result.Map
+	if title == "" {
+		title = strings.TrimSuffix(demo, filepath.Ext(demo))
+	}
+	fmt.Fprintf(sb, "## %s\n\n- Demo: `%s`\n", title, demo)
+	if result.Score != nil {
+		fmt.Fprintf(sb, "- Score: %s\n", scoreLine(*result.Score))
+	}
+	if result.SteamID != "" {
+		fmt.Fprintf(sb, "- Player: %s\n", result.SteamID)
+	}
+	sb.WriteString("\n")
+	if result.Stats == nil {
+		return
+	}
+	t := result.Stats.Totals
+	sb.WriteString("| K/D/A | ADR | HS | KAST | Opening | Clutches | Rounds |\n| --- | ---: | ---: | ---: | --- | --- | ---: |\n")
+	fmt.Fprintf(sb, "| %d/%d/%d | %.1f | %.0f%% | %.0f%% | %d/%d | %d/%d | %d |\n\n",
+		t.Kills, t.Deaths, t.Assists, t.ADR, t.HSPercent, t.KASTPercent,
+		t.OpeningKills, t.OpeningDeaths, t.ClutchesWon, t.ClutchesAttempted, t.Rounds)
+}
+
+// scoreLine reads the score from the player's side when it is known:
+// "13:9 win (Team A vs Team B)", otherwise "CT 13:9 T".
+func scoreLine(score model.MatchScore) string {
+	own, other := score.CT, score.T
+	switch score.Side {
+	case "T":
+		own, other = score.T, score.CT
+	case "":
+		return fmt.Sprintf("%s %d:%d %s", teamName(score.CT, "CT"), score.CT.Score, score.T.Score, teamName(score.T, "T"))
+	}
+	outcome := "draw"
+	switch {
+	case own.Score > other.Score:
+		outcome = "win"
+	case own.Score < other.Score:
+		outcome = "loss"
+	}
+	line := fmt.Sprintf("%d:%d %s", own.Score, other.Score, outcome)
+	if own.Name != "" && other.Name != "" {
+		line += fmt.Sprintf(" (%s vs %s)", own.Name, other.Name)
+	}
+	return line
+}
+
+func teamName(team model.TeamScore, side string) string {
+	if team.Name == "" {
+		return side
+	}
+	return team.Name
+}
+
+// highlightCounts lists the rounds of every highlight in the video by type.
+// A highlight in several takes, like a clip and its replays, counts once.
+func highlightCounts(manifest hlae.Manifest) map[model.HighlightType][]int {
+	counts := make(map[model.HighlightType][]int)
+	seen := make(map[string]bool)
+	for _, recording := range manifest.Recordings {
+		for _, h := range recording.Highlights {
+			key := fmt.Sprintf("%s/%s/%d", recording.Demo, h.Type, h.TickStart)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			counts[h.Type] = append(counts[h.Type], h.Round)
+		}
+	}
+	return counts
+}
+
+func joinRounds(rounds []int) string {
+	parts := make([]string, 0, len(rounds))
+	for _, round := range rounds {
+		parts = append(parts, strconv.Itoa(round))
+	}
+	return strings.Join(parts, ", ")
+}
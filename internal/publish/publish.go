@@ -0,0 +1,203 @@
+// Package publish writes the text that goes with an uploaded montage: a
+// YouTube chapter list timed from the recording manifest, and a Markdown
+// description with the map, score, player stats and highlights.
+package publish
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/captions"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// Writer writes chapters and descriptions. Captions titles the chapters,
+// the same way the video's captions read.
+type Writer struct {
+	Captions captions.Captioner
+}
+
+func NewWriter() *Writer {
+	return &Writer{Captions: *captions.NewCaptioner()}
+}
+
+// Chapter starts at At seconds into the montage.
+type Chapter struct {
+	At    float64
+	Title string
+}
+
+const titleSeparator = " / "
+
+// YouTube only reads a chapter list that starts at 00:00 and has at least
+// MinChapters chapters of at least MinChapterSeconds each.
+const (
+	MinChapters       = 3
+	MinChapterSeconds = 10
+)
+
+// Chapters lists a chapter per segment of a montage, at the second its
+// segment starts in the recorded takes played back to back. Replays stay in
+// the chapter of their segment, and a chapter shorter than MinChapterSeconds
+// is merged into its neighbour. Clips get none.
+func (w *Writer) Chapters(manifest hlae.Manifest) []Chapter {
+	if manifest.Mode != hlae.ModeMontage.String() {
+		return nil
+	}
+	var chapters []Chapter
+	var starts, others [][]string
+	offset := 0.0
+	for _, recording := range manifest.Recordings {
+		for i, cut := range recording.Cuts {
+			opens := i == 0 || !recording.Continues(recording.Cuts[i-1], cut)
+			if len(chapters) == 0 || opens && cut.ReplaySlot == 0 {
+				chapters = append(chapters, Chapter{At: offset + cut.At})
+				starts, others = append(starts, nil), append(others, nil)
+			}
+			n := len(chapters) - 1
+			end := cut.At + cut.Duration
+			for _, h := range recording.Highlights {
+				first, hit := false, false
+				for k, at := range h.KillTimes {
+					if at >= cut.At && at <= end {
+						hit, first = true, first || k == 0
+					}
+				}
+				if !hit || cut.ReplaySlot != 0 {
+					continue
+				}
+				title := w.Captions.Text(manifest, recording, h)
+				if first {
+					starts[n] = appendTitle(starts[n], title)
+				} else {
+					others[n] = appendTitle(others[n], title)
+				}
+			}
+		}
+		offset += recording.Duration
+	}
+
+	// A kill streak plays across segments; it titles the one it starts in,
+	// and only the segments nothing starts in otherwise.
+	var out []Chapter
+	for i, chapter := range chapters {
+		titles := starts[i]
+		if len(titles) == 0 {
+			titles = others[i]
+		}
+		if len(titles) == 0 {
+			titles = []string{fmt.Sprintf("Highlight %d", i+1)}
+		}
+		chapter.At = math.Floor(chapter.At)
+		if i == 0 {
+			chapter.At = 0
+		}
+		if n := len(out); n > 0 && out[n-1].At == chapter.At {
+			for _, title := range titles {
+				out[n-1].Title = joinTitle(out[n-1].Title, title)
+			}
+			continue
+		}
+		chapter.Title = strings.Join(titles, titleSeparator)
+		out = append(out, chapter)
+	}
+	return mergeShort(out, offset)
+}
+
+// mergeShort folds each chapter shorter than MinChapterSeconds into the one
+// before it, and a short first chapter into the one after. total is the
+// montage's length, where the last chapter ends.
+func mergeShort(chapters []Chapter, total float64) []Chapter {
+	if len(chapters) == 0 {
+		return nil
+	}
+	var out []Chapter
+	current := chapters[0]
+	for _, next := range chapters[1:] {
+		if next.At-current.At < MinChapterSeconds {
+			current.Title = mergeTitles(current.Title, next.Title)
+			continue
+		}
+		out = append(out, current)
+		current = next
+	}
+	if n := len(out); n > 0 && total-current.At < MinChapterSeconds {
+		out[n-1].Title = mergeTitles(out[n-1].Title, current.Title)
+		return out
+	}
+	return append(out, current)
+}
+
+func mergeTitles(title, next string) string {
+	for _, part := range strings.Split(next, titleSeparator) {
+		title = joinTitle(title, part)
+	}
+	return title
+}
+
+// CheckChapters reports why YouTube would not read chapters as a chapter
+// list, or nil when it would. Chapters already start at 00:00 and last
+// MinChapterSeconds; only their count is left to check.
+func CheckChapters(chapters []Chapter) error {
+	if len(chapters) < MinChapters {
+		return fmt.Errorf("YouTube needs at least %d chapters of %ds or more, the montage has %d", MinChapters, MinChapterSeconds, len(chapters))
+	}
+	return nil
+}
+
+func appendTitle(titles []string, title string) []string {
+	if slices.Contains(titles, title) {
+		return titles
+	}
+	return append(titles, title)
+}
+
+func joinTitle(title, next string) string {
+	if strings.Contains(titleSeparator+title+titleSeparator, titleSeparator+next+titleSeparator) {
+		return title
+	}
+	return title + titleSeparator + next
+}
+
+// ChapterList renders chapters as YouTube reads them from a description:
+// one "mm:ss Title" line each, h:mm:ss past the hour.
+func ChapterList(chapters []Chapter) string {
+	var sb strings.Builder
+	for _, chapter := range chapters {
+		fmt.Fprintf(&sb, "%s %s\n", Timestamp(chapter.At), chapter.Title)
+	}
+	return sb.String()
+}
+
+// Timestamp formats whole seconds as mm:ss, or h:mm:ss from an hour on.
+func Timestamp(seconds float64) string {
+	s := int(seconds)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+// ChaptersPath and DescriptionPath are written next to the script at
+// scriptPath.
+func ChaptersPath(scriptPath string) string {
+	return strings.TrimSuffix(scriptPath, filepath.Ext(scriptPath)) + ".chapters.txt"
+}
+
+func DescriptionPath(scriptPath string) string {
+	return strings.TrimSuffix(scriptPath, filepath.Ext(scriptPath)) + ".description.md"
+}
+
+// Files writes the description of the script at scriptPath over the
+// results it was built from, and its chapter list when it has chapters.
+func (w *Writer) Files(manifest hlae.Manifest, results []model.HighlightResult, chapters []Chapter, scriptPath string) []hlae.File {
+	files := []hlae.File{{Path: DescriptionPath(scriptPath), Content: w.Description(manifest, results, chapters)}}
+	if len(chapters) > 0 {
+		files = append(files, hlae.File{Path: ChaptersPath(scriptPath), Content: ChapterList(chapters)})
+	}
+	return files
+}
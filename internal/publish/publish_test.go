@@ -0,0 +1,157 @@
+package publish
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+func testResult() model.HighlightResult {
+	return model.HighlightResult{
+		Demo:     "/demos/mirage.dem",
+		SteamID:  "76561198000000001",
+		TickRate: 64,
+		Map:      "de_mirage",
+		Score:    &model.MatchScore{CT: model.TeamScore{Name: "Blue", Score: 9}, T: model.TeamScore{Name: "Red", Score: 13}, Side: "T"},
+		Stats:    &model.PlayerStats{Totals: model.StatsTotals{Rounds: 22, Kills: 24, Deaths: 15, Assists: 4, ADR: 95.24, HSPercent: 54.2, KASTPercent: 77.7, OpeningKills: 5, OpeningDeaths: 2, ClutchesWon: 1, ClutchesAttempted: 3}},
+		Highlights: []model.Highlight{
+			{Type: model.HighlightHeadshot, Round: 3, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000, TickEnd: 1000, Weapon: "AK-47"},
+			{Type: model.HighlightWallbang, Round: 9, PlayerSlot: 7, SegmentFrom: 5000, SegmentTo: 5000, TickStart: 5000, TickEnd: 5000},
+			{Type: model.HighlightHeadshot, Round: 14, PlayerSlot: 7, SegmentFrom: 9000, SegmentTo: 9000, TickStart: 9000, TickEnd: 9000},
+		},
+	}
+}
+
+func buildManifest(t *testing.T, result model.HighlightResult, options hlae.Options, mode hlae.Mode) hlae.Manifest {
+	t.Helper()
+	files, err := hlae.BuildTarget(result, options, hlae.Target{Mode: mode, Path: "reel.cfg", Name: "reel"})
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	var manifest hlae.Manifest
+	if err := json.Unmarshal([]byte(files[len(files)-1].Content), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	return manifest
+}
+
+func TestChaptersStartAtMontageSegments(t *testing.T) {
+	options := hlae.Options{OutputPath: "/rec", PreRollSeconds: 3, PostRollSeconds: 7, SlowMotionScale: 0.5, SlowMotionSeconds: 1}
+	manifest := buildManifest(t, testResult(), options, hlae.ModeMontage)
+
+	// Each segment is 10s of demo; 2s of it slowed to half speed plays 4s.
+	want := []Chapter{
+		{At: 0, Title: "Round 3 — headshot — AK-47"},
+		{At: 12, Title: "Round 9 — wallbang"},
+		{At: 24, Title: "Round 14 — headshot"},
+	}
+	got := NewWriter().Chapters(manifest)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	var segmentStarts []float64
+	recording := manifest.Recordings[0]
+	for i, cut := range recording.Cuts {
+		if i == 0 || !recording.Continues(recording.Cuts[i-1], cut) {
+			segmentStarts = append(segmentStarts, cut.At)
+		}
+	}
+	if !reflect.DeepEqual(segmentStarts, []float64{0, 12, 24}) {
+		t.Fatalf("expected chapters on the recorded segment starts, got %v", segmentStarts)
+	}
+
+	if list := ChapterList(got); list != "00:00 Round 3 — headshot — AK-47\n00:12 Round 9 — wallbang\n00:24 Round 14 — headshot\n" {
+		t.Fatalf("unexpected chapter list:\n%s", list)
+	}
+	if clips := NewWriter().Chapters(buildManifest(t, testResult(), options, hlae.ModeClips)); clips != nil {
+		t.Fatalf("expected clips to get no chapters, got %+v", clips)
+	}
+}
+
+func TestChaptersKeepReplaysAndMergeSameSecond(t *testing.T) {
+	manifest := hlae.Manifest{Mode: "montage", Recordings: []hlae.ManifestRecording{
+		{Duration: 62.5, Cuts: []hlae.ManifestCut{
+			{TickStart: 0, TickEnd: 320, At: 0, Duration: 5},
+			{TickStart: 100, TickEnd: 228, At: 5, Duration: 2, ReplaySlot: 4},
+			{TickStart: 2000, TickEnd: 5520, At: 7, Duration: 55},
+			{TickStart: 9000, TickEnd: 9032, At: 62, Duration: 0.5},
+		}, Highlights: []hlae.ManifestHighlight{
+			{Type: model.HighlightNoScope, Round: 1, KillTimes: []float64{3}},
+			{Type: model.HighlightKillStreak, Round: 1, KillTimes: []float64{3, 40}, Meta: map[string]string{"streak": "2 rounds"}},
+			{Type: model.HighlightHeadshot, Round: 2, KillTimes: []float64{40}},
+			{Type: model.HighlightHeadshot, Round: 9, KillTimes: []float64{62.2}},
+		}},
+		{Duration: 3600, Cuts: []hlae.ManifestCut{{TickStart: 0, TickEnd: 320, At: 0, Duration: 3600}}},
+	}}
+	// The 7s chapter of round 1 is merged into round 2's.
+	want := []Chapter{
+		{At: 0, Title: "Round 1 — noscope / Round 1 — kill streak (2 rounds) / Round 2 — headshot"},
+		{At: 62, Title: "Round 9 — headshot / Highlight 4"},
+	}
+	if got := NewWriter().Chapters(manifest); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if got := Timestamp(3725.9); got != "1:02:05" {
+		t.Fatalf("expected h:mm:ss past the hour, got %s", got)
+	}
+}
+
+func TestFilesDescribeMatchAndHighlights(t *testing.T) {
+	result := testResult()
+	manifest := buildManifest(t, result, hlae.Options{OutputPath: "/rec", PreRollSeconds: 3, PostRollSeconds: 8}, hlae.ModeMontage)
+	writer := NewWriter()
+	files := writer.Files(manifest, []model.HighlightResult{result}, writer.Chapters(manifest), "out/reel.cfg")
+	if len(files) != 2 || files[0].Path != "out/reel.description.md" || files[1].Path != "out/reel.chapters.txt" {
+		t.Fatalf("expected a description and chapters, got %+v", files)
+	}
+	for _, want := range []string{
+		"## de_mirage\n\n- Demo: `mirage.dem`\n- Score: 13:9 win (Red vs Blue)\n- Player: 76561198000000001\n",
+		"| 24/15/4 | 95.2 | 54% | 78% | 5/2 | 1/3 | 22 |\n",
+		"| wallbang | 1 | 9 |\n| headshot_kill | 2 | 3, 14 |\n",
+		"## Chapters\n\n00:00 Round 3 — headshot — AK-47\n00:11 Round 9 — wallbang\n",
+	} {
+		if !strings.Contains(files[0].Content, want) {
+			t.Fatalf("expected %q in description:\n%s", want, files[0].Content)
+		}
+	}
+	if got := scoreLine(model.MatchScore{CT: model.TeamScore{Score: 8}, T: model.TeamScore{Name: "Red", Score: 13}}); got != "CT 8:13 Red" {
+		t.Fatalf("unexpected score without a side: %s", got)
+	}
+}
+
+func TestChaptersMergeShortSegmentsForYouTube(t *testing.T) {
+	// 5s segments: the first two merge into a 10s chapter, and the last,
+	// 5s short of the end, is merged into it as well.
+	manifest := buildManifest(t, testResult(), hlae.Options{OutputPath: "/rec", PreRollSeconds: 3, PostRollSeconds: 2}, hlae.ModeMontage)
+	writer := NewWriter()
+	chapters := writer.Chapters(manifest)
+	want := []Chapter{{At: 0, Title: "Round 3 — headshot — AK-47 / Round 9 — wallbang / Round 14 — headshot"}}
+	if !reflect.DeepEqual(chapters, want) {
+		t.Fatalf("expected %+v, got %+v", want, chapters)
+	}
+	if err := CheckChapters(chapters); err == nil || !strings.Contains(err.Error(), "YouTube needs at least 3 chapters") {
+		t.Fatalf("expected too few chapters to be reported, got %v", err)
+	}
+
+	got := mergeShort([]Chapter{{At: 0, Title: "a"}, {At: 4, Title: "b"}, {At: 12, Title: "c"}, {At: 30, Title: "d"}, {At: 45, Title: "e"}}, 50)
+	want = []Chapter{{At: 0, Title: "a / b"}, {At: 12, Title: "c"}, {At: 30, Title: "d / e"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	for i, chapter := range got {
+		end := 50.0
+		if i+1 < len(got) {
+			end = got[i+1].At
+		}
+		if end-chapter.At < MinChapterSeconds {
+			t.Fatalf("chapter %+v lasts under %ds", chapter, MinChapterSeconds)
+		}
+	}
+	if err := CheckChapters(got); err != nil {
+		t.Fatalf("expected three 10s chapters to pass, got %v", err)
+	}
+}
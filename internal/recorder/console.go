@@ -0,0 +1,128 @@
+package recorder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+const (
+	defaultConsoleKey = "F8"
+	consoleAlias      = "hlp"
+)
+
+// ConsoleRecorder renders plain CS2 console scripts, without mirv commands,
+// for editors who capture the screen with OBS or similar. The game cannot
+// schedule commands at a tick on its own, so the script binds Key to step
+// through the segments: each press seeks to the next segment, locks the POV
+// and plays it, and echoes where it ends. Clips are recorded one capture per
+// segment, a montage as one capture across every press.
+//
+// Only the HUD preset carries over from the HLAE options; slow motion,
+// replays, intros, death notice tweaks and stream layers need HLAE.
+type ConsoleRecorder struct {
+	Options hlae.Options
+	Key     string
+}
+
+func NewConsoleRecorder(options hlae.Options) *ConsoleRecorder {
+	return &ConsoleRecorder{
+		Options: options,
+		Key:     defaultConsoleKey,
+	}
+}
+
+func (r *ConsoleRecorder) Render(result model.HighlightResult, target hlae.Target) ([]hlae.File, error) {
+	if len(target.Layers) > 0 {
+		return nil, fmt.Errorf("render target %q: stream layers need the %s recorder", target.Path, HLAE)
+	}
+	segs, options := hlae.PlanSegments(result, r.Options, target)
+
+	var w strings.Builder
+	writeConsoleSetup(&w, options.HUD.Preset)
+
+	if len(segs) == 0 {
+		writeLine(&w, `echo "No highlights found."`)
+		return []hlae.File{{Path: target.Path, Content: w.String()}}, nil
+	}
+
+	for i, seg := range segs {
+		next := fmt.Sprintf("%s_seg%d", consoleAlias, i+2)
+		if i == len(segs)-1 {
+			next = consoleAlias + "_done"
+		}
+		commands := []string{"demo_pause", fmt.Sprintf("demo_gototick %d", seg.StartTick)}
+		if seg.PlayerSlot > 0 {
+			commands = append(commands, fmt.Sprintf("spec_player %d", seg.PlayerSlot))
+		}
+		commands = append(commands,
+			fmt.Sprintf("echo %s", segmentLabel(i, len(segs), seg, result.TickRate)),
+			fmt.Sprintf("alias %s_next %s", consoleAlias, next),
+			"demo_resume",
+		)
+		writeLine(&w, fmt.Sprintf(`alias %s_seg%d "%s"`, consoleAlias, i+1, strings.Join(commands, "; ")))
+	}
+	writeLine(&w, fmt.Sprintf(`alias %s_done "demo_pause; echo All %d segments played"`, consoleAlias, len(segs)))
+	writeLine(&w, fmt.Sprintf("alias %s_next %s_seg1", consoleAlias, consoleAlias))
+	writeLine(&w, fmt.Sprintf("bind %s %s_next", r.Key, consoleAlias))
+	w.WriteString("\n")
+
+	if target.Mode == hlae.ModeMontage {
+		writeLine(&w, fmt.Sprintf(`echo "Start one capture, then press %s for each of the %d segments and stop it after the last."`, r.Key, len(segs)))
+	} else {
+		writeLine(&w, fmt.Sprintf(`echo "Press %s to play each of the %d segments, capturing each from the press to its end tick."`, r.Key, len(segs)))
+	}
+	return []hlae.File{{Path: target.Path, Content: w.String()}}, nil
+}
+
+// writeConsoleSetup pauses the demo and hides the spectator overlays, then
+// draws the HUD preset.
+func writeConsoleSetup(w *strings.Builder, hud hlae.HUDPreset) {
+	writeLine(w, "demo_pause")
+	writeLine(w, "spec_show_xray 0")
+	writeLine(w, "demoui 0")
+	writeLine(w, "cl_trueview_show_status 0")
+	writeLine(w, "r_show_build_info false")
+	for _, line := range consoleHUDCommands(hud) {
+		writeLine(w, line)
+	}
+	w.WriteString("\n")
+}
+
+// consoleHUDCommands draws the HUD preset with plain cvars. Without
+// mirv_deathmsg the death notices cannot be filtered, so the killfeed preset
+// shows every notice, like highlight.
+func consoleHUDCommands(preset hlae.HUDPreset) []string {
+	switch preset {
+	case hlae.HUDFull:
+		return []string{"cl_drawhud 1", "cl_draw_only_deathnotices 0"}
+	case hlae.HUDClean:
+		return []string{"cl_drawhud 0"}
+	}
+	return []string{"cl_drawhud 1", "cl_draw_only_deathnotices 1"}
+}
+
+// segmentLabel is the echo text of a segment. It goes unquoted inside an
+// alias body, so it has no quotes or semicolons.
+func segmentLabel(i, count int, seg hlae.Segment, tickRate float64) string {
+	var types []string
+	for _, h := range seg.Highlights {
+		types = append(types, string(h.Type))
+	}
+	round := ""
+	if len(seg.Highlights) > 0 {
+		round = fmt.Sprintf(" round %d", seg.Highlights[0].Round)
+	}
+	label := fmt.Sprintf("Segment %d of %d -%s %s - ticks %d to %d", i+1, count, round, strings.Join(types, ","), seg.StartTick, seg.EndTick)
+	if tickRate > 0 {
+		label += fmt.Sprintf(" - %.1fs", float64(seg.EndTick-seg.StartTick)/tickRate)
+	}
+	return label
+}
+
+func writeLine(w *strings.Builder, line string) {
+	w.WriteString(line)
+	w.WriteString(";\n")
+}
@@ -0,0 +1,135 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/obs"
+)
+
+// OBSRecorder renders a demo-control cfg and an OBS plan for the obs driver.
+// The cfg seeks to the first segment, locks the POV and pauses, binding Key
+// to resume; from that press the demo plays through in real time while the
+// driver starts and stops OBS at each segment's wall-clock time. Clips get
+// one recording per segment; a montage is one recording, paused between
+// segments.
+//
+// Playback runs straight through, so the gaps between segments play out and
+// the POV stays on one player; targets whose highlights follow other
+// players are rejected. As with the console recorder, only the pre- and
+// post-roll and the HUD preset carry over.
+type OBSRecorder struct {
+	Options hlae.Options
+	Key     string
+}
+
+func NewOBSRecorder(options hlae.Options) *OBSRecorder {
+	return &OBSRecorder{
+		Options: options,
+		Key:     defaultConsoleKey,
+	}
+}
+
+func (r *OBSRecorder) Render(result model.HighlightResult, target hlae.Target) ([]hlae.File, error) {
+	if len(target.Layers) > 0 {
+		return nil, fmt.Errorf("render target %q: stream layers need the %s recorder", target.Path, HLAE)
+	}
+	if result.TickRate <= 0 {
+		return nil, fmt.Errorf("render target %q: the %s recorder needs the demo's tick rate", target.Path, OBS)
+	}
+	segs, options := hlae.PlanSegments(result, r.Options, target)
+	slot, ok := povSlot(segs)
+	if !ok {
+		return nil, fmt.Errorf("render target %q: the %s recorder holds one POV, but its highlights follow several players; use the %s or %s recorder", target.Path, OBS, Console, HLAE)
+	}
+
+	var w strings.Builder
+	writeConsoleSetup(&w, options.HUD.Preset)
+
+	plan := obs.Plan{
+		Demo:     result.Demo,
+		Target:   target.Name,
+		Mode:     target.Mode.String(),
+		TickRate: result.TickRate,
+		Key:      r.Key,
+		Steps:    make([]obs.Step, 0),
+	}
+	if len(segs) == 0 {
+		writeLine(&w, `echo "No highlights found."`)
+	} else {
+		plan.StartTick = segs[0].StartTick
+		plan.Steps = planSteps(segs, target.Mode, result.TickRate)
+		writeLine(&w, fmt.Sprintf("demo_gototick %d", plan.StartTick))
+		if slot > 0 {
+			writeLine(&w, fmt.Sprintf("spec_player %d", slot))
+		}
+		writeLine(&w, fmt.Sprintf("bind %s demo_resume", r.Key))
+		writeLine(&w, fmt.Sprintf(`echo "Start the OBS driver and press %s when it says so; %d segments."`, r.Key, len(segs)))
+	}
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []hlae.File{
+		{Path: target.Path, Content: w.String()},
+		{Path: PlanPath(target.Path), Content: string(data) + "\n"},
+	}, nil
+}
+
+// povSlot is the one player every highlight of segs is seen from, the one
+// POV a straight playback can hold; ok is false when they follow several.
+// Highlights of no known player (slot 0) follow whichever POV is held.
+func povSlot(segs []hlae.Segment) (slot int, ok bool) {
+	for _, seg := range segs {
+		for _, h := range seg.Highlights {
+			if h.PlayerSlot == 0 {
+				continue
+			}
+			if slot == 0 {
+				slot = h.PlayerSlot
+			}
+			if h.PlayerSlot != slot {
+				return 0, false
+			}
+		}
+	}
+	return slot, true
+}
+
+// PlanPath is where the OBS plan of the script at path is written:
+// <script base>.obs.json.
+func PlanPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".obs.json"
+}
+
+// planSteps times the recording requests of segs from the first segment's
+// start tick, in seconds rounded to milliseconds.
+func planSteps(segs []hlae.Segment, mode hlae.Mode, tickRate float64) []obs.Step {
+	origin := segs[0].StartTick
+	at := func(tick int) float64 {
+		return math.Round(float64(tick-origin)/tickRate*1000) / 1000
+	}
+	steps := make([]obs.Step, 0, 2*len(segs))
+	for i, seg := range segs {
+		start, end := obs.StartRecord, obs.StopRecord
+		if mode == hlae.ModeMontage {
+			if i > 0 {
+				start = obs.ResumeRecord
+			}
+			if i < len(segs)-1 {
+				end = obs.PauseRecord
+			}
+		}
+		steps = append(steps,
+			obs.Step{At: at(seg.StartTick), Request: start, Segment: i},
+			obs.Step{At: at(seg.EndTick), Request: end, Segment: i},
+		)
+	}
+	return steps
+}
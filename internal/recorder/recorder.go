@@ -0,0 +1,86 @@
+// Package recorder turns highlight results into the files a recording tool
+// runs. Each render target picks its backend by name: HLAE scripts that
+// record by themselves, plain CS2 console scripts for screen recorders such
+// as OBS, or a cfg plus an OBS plan that the obs driver records from.
+package recorder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// Backend names, as selected by hlae.Target.Recorder.
+const (
+	HLAE    = "hlae"
+	Console = "console"
+	OBS     = "obs"
+)
+
+func Names() []string {
+	return []string{HLAE, Console, OBS}
+}
+
+// Recorder renders a target over one demo's highlights into the files that
+// record it.
+type Recorder interface {
+	Render(result model.HighlightResult, target hlae.Target) ([]hlae.File, error)
+}
+
+// Chainer is a Recorder that can also record a target across several demos
+// in one run.
+type Chainer interface {
+	Recorder
+	RenderChain(results []model.HighlightResult, target hlae.Target) ([]hlae.File, error)
+}
+
+// New returns the backend called name ("" is HLAE), rendering with the
+// shared options.
+func New(name string, options hlae.Options) (Recorder, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", HLAE:
+		return NewHLAERecorder(options), nil
+	case Console:
+		return NewConsoleRecorder(options), nil
+	case OBS:
+		return NewOBSRecorder(options), nil
+	}
+	return nil, fmt.Errorf("unknown recorder %q (valid: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Render renders target with its own backend: over the one result, or
+// chained over several when the backend can chain.
+func Render(results []model.HighlightResult, options hlae.Options, target hlae.Target) ([]hlae.File, error) {
+	rec, err := New(target.Recorder, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 1 {
+		return rec.Render(results[0], target)
+	}
+	chainer, ok := rec.(Chainer)
+	if !ok {
+		return nil, fmt.Errorf("render target %q: the %s recorder cannot chain several demos", target.Path, target.Recorder)
+	}
+	return chainer.RenderChain(results, target)
+}
+
+// HLAERecorder renders mirv scripts that seek, switch POV and record by
+// themselves.
+type HLAERecorder struct {
+	Options hlae.Options
+}
+
+func NewHLAERecorder(options hlae.Options) *HLAERecorder {
+	return &HLAERecorder{Options: options}
+}
+
+func (r *HLAERecorder) Render(result model.HighlightResult, target hlae.Target) ([]hlae.File, error) {
+	return hlae.BuildTarget(result, r.Options, target)
+}
+
+func (r *HLAERecorder) RenderChain(results []model.HighlightResult, target hlae.Target) ([]hlae.File, error) {
+	return hlae.BuildChain(results, r.Options, target)
+}
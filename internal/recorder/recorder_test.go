@@ -0,0 +1,152 @@
+package recorder
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/obs"
+)
+
+func testResult() model.HighlightResult {
+	return model.HighlightResult{
+		Demo:     "mirage.dem",
+		TickRate: 64,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightHeadshot, Round: 3, PlayerSlot: 7, SegmentFrom: 1000, SegmentTo: 1000, TickStart: 1000},
+			{Type: model.HighlightWallbang, Round: 9, PlayerSlot: 7, SegmentFrom: 5000, SegmentTo: 5064, TickStart: 5000},
+		},
+	}
+}
+
+func TestConsoleRecorderStepsThroughSegments(t *testing.T) {
+	options := hlae.Options{PreRollSeconds: 1, PostRollSeconds: 1, HUD: hlae.HUD{Preset: hlae.HUDClean}}
+	files, err := Render([]model.HighlightResult{testResult()}, options, hlae.Target{Mode: hlae.ModeClips, Path: "plain.cfg", Recorder: Console})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "plain.cfg" {
+		t.Fatalf("expected one script, got %+v", files)
+	}
+	script := files[0].Content
+	for _, want := range []string{
+		"cl_drawhud 0;\n",
+		`alias hlp_seg1 "demo_pause; demo_gototick 936; spec_player 7; echo Segment 1 of 2 - round 3 headshot_kill - ticks 936 to 1064 - 2.0s; alias hlp_next hlp_seg2; demo_resume";`,
+		`alias hlp_seg2 "demo_pause; demo_gototick 4936; spec_player 7; echo Segment 2 of 2 - round 9 wallbang - ticks 4936 to 5128 - 3.0s; alias hlp_next hlp_done; demo_resume";`,
+		"alias hlp_next hlp_seg1;\nbind F8 hlp_next;\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("expected %q in script:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "mirv_") {
+		t.Fatalf("expected no mirv commands:\n%s", script)
+	}
+
+	report := hlae.Lint(script, hlae.LintOptions{})
+	if report.Errors() > 0 {
+		t.Fatalf("expected the script to lint clean, got %v", report.Issues)
+	}
+
+	// A merged team segment is split where the POV switches, one press per
+	// player.
+	team := testResult()
+	team.Highlights[1] = model.Highlight{Type: model.HighlightNoScope, Round: 3, PlayerSlot: 3, SegmentFrom: 1010, SegmentTo: 1010, TickStart: 1010}
+	files, err = Render([]model.HighlightResult{team}, options, hlae.Target{Mode: hlae.ModeClips, Path: "plain.cfg", Recorder: Console})
+	if err != nil {
+		t.Fatalf("render team: %v", err)
+	}
+	for _, want := range []string{
+		`alias hlp_seg1 "demo_pause; demo_gototick 936; spec_player 7; echo Segment 1 of 2 - round 3 headshot_kill - ticks 936 to 1001`,
+		`alias hlp_seg2 "demo_pause; demo_gototick 1001; spec_player 3; echo Segment 2 of 2 - round 3 noscope - ticks 1001 to 1074`,
+	} {
+		if !strings.Contains(files[0].Content, want) {
+			t.Fatalf("expected %q in script:\n%s", want, files[0].Content)
+		}
+	}
+
+	if _, err := Render([]model.HighlightResult{testResult()}, options, hlae.Target{Path: "plain.cfg", Recorder: Console, Layers: []hlae.StreamLayer{{Name: "world", Kind: hlae.LayerWorld}}}); err == nil {
+		t.Fatalf("expected stream layers to need HLAE")
+	}
+}
+
+func TestOBSRecorderTimesRecordingsFromTheFirstSegment(t *testing.T) {
+	options := hlae.Options{PreRollSeconds: 1, PostRollSeconds: 1}
+	files, err := Render([]model.HighlightResult{testResult()}, options, hlae.Target{Mode: hlae.ModeMontage, Path: "out/obs.cfg", Name: "obs", Recorder: OBS})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if len(files) != 2 || files[1].Path != "out/obs.obs.json" {
+		t.Fatalf("expected the cfg and its plan, got %+v", files)
+	}
+	if !strings.Contains(files[0].Content, "demo_gototick 936;\nspec_player 7;\nbind F8 demo_resume;\n") {
+		t.Fatalf("expected the cfg to wait at the first segment:\n%s", files[0].Content)
+	}
+
+	var plan obs.Plan
+	if err := json.Unmarshal([]byte(files[1].Content), &plan); err != nil {
+		t.Fatalf("decode plan: %v", err)
+	}
+	want := []obs.Step{
+		{At: 0, Request: obs.StartRecord, Segment: 0},
+		{At: 2, Request: obs.PauseRecord, Segment: 0},
+		{At: 62.5, Request: obs.ResumeRecord, Segment: 1},
+		{At: 65.5, Request: obs.StopRecord, Segment: 1},
+	}
+	if plan.StartTick != 936 || !reflect.DeepEqual(plan.Steps, want) {
+		t.Fatalf("expected montage steps %+v from tick 936, got %+v from %d", want, plan.Steps, plan.StartTick)
+	}
+
+	segs, _ := hlae.PlanSegments(testResult(), options, hlae.Target{})
+	clips := planSteps(segs, hlae.ModeClips, 64)
+	if clips[1].Request != obs.StopRecord || clips[2].Request != obs.StartRecord {
+		t.Fatalf("expected clips to stop and start per segment, got %+v", clips)
+	}
+
+	// Another player's segment, or a POV switch inside one, cannot be
+	// recorded from a straight playback.
+	for _, tick := range []int{5000, 1010} {
+		result := testResult()
+		result.Highlights[1] = model.Highlight{Type: model.HighlightNoScope, Round: 3, PlayerSlot: 3, SegmentFrom: tick, SegmentTo: tick, TickStart: tick}
+		_, err := Render([]model.HighlightResult{result}, options, hlae.Target{Mode: hlae.ModeClips, Path: "obs.cfg", Name: "obs", Recorder: OBS})
+		if err == nil || !strings.Contains(err.Error(), Console) {
+			t.Fatalf("tick %d: expected the other player's POV to be rejected, got %v", tick, err)
+		}
+	}
+
+	// Highlights of an unknown slot do not count as another player.
+	result := testResult()
+	result.Highlights[0].PlayerSlot = 0
+	files, err = Render([]model.HighlightResult{result}, options, hlae.Target{Mode: hlae.ModeClips, Path: "obs.cfg", Name: "obs", Recorder: OBS})
+	if err != nil {
+		t.Fatalf("expected an unknown slot to pass, got %v", err)
+	}
+	if !strings.Contains(files[0].Content, "spec_player 7;\n") {
+		t.Fatalf("expected the POV of the known player:\n%s", files[0].Content)
+	}
+}
+
+func TestRenderPicksTheTargetsRecorder(t *testing.T) {
+	results := []model.HighlightResult{testResult(), testResult()}
+
+	files, err := Render(results[:1], hlae.Options{}, hlae.Target{Mode: hlae.ModeMontage, Path: "reel.cfg", Name: "reel"})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if len(files) != 2 || !strings.Contains(files[0].Content, "mirv_streams record start") || files[1].Path != "reel.manifest.json" {
+		t.Fatalf("expected the HLAE script and manifest by default, got %+v", files)
+	}
+
+	if _, err := Render(results, hlae.Options{}, hlae.Target{Mode: hlae.ModeMontage, Path: "reel.cfg", Name: "reel", Recorder: HLAE}); err != nil {
+		t.Fatalf("expected HLAE to chain demos: %v", err)
+	}
+	if _, err := Render(results, hlae.Options{}, hlae.Target{Path: "reel.cfg", Recorder: Console}); err == nil {
+		t.Fatalf("expected the console recorder to refuse a chain")
+	}
+	if _, err := New("vlc", hlae.Options{}); err == nil {
+		t.Fatalf("expected error for an unknown recorder")
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// JSONEncoder writes the whole result as one indented document.
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(w io.Writer, result model.HighlightResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// NDJSONEncoder writes one highlight per line, each carrying the fields of
+// its demo so lines can be loaded on their own. Stats are left out.
+type NDJSONEncoder struct{}
+
+// ndjsonRecord is a highlight with its demo's fields. The final score is
+// match_score, since a highlight has a score of its own.
+type ndjsonRecord struct {
+	model.Highlight
+	SchemaVersion int               `json:"schema_version,omitempty"`
+	TickRate      float64           `json:"tick_rate"`
+	Map           string            `json:"map,omitempty"`
+	MatchScore    *model.MatchScore `json:"match_score,omitempty"`
+	Perspective   model.Perspective `json:"perspective,omitempty"`
+}
+
+func (NDJSONEncoder) Encode(w io.Writer, result model.HighlightResult) error {
+	enc := json.NewEncoder(w)
+	for _, h := range result.Highlights {
+		record := ndjsonRecord{
+			Highlight:     h,
+			SchemaVersion: result.SchemaVersion,
+			TickRate:      result.TickRate,
+			Map:           result.Map,
+			MatchScore:    result.Score,
+			Perspective:   result.Perspective,
+		}
+		if record.Demo == "" {
+			record.Demo = result.Demo
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSVEncoder writes a row per highlight with its demo's fields. Lists are
+// joined with ";" and every meta key gets a meta_<key> column. Scenes and
+// stats are left out.
+type CSVEncoder struct{}
+
+var csvColumns = []string{
+	"demo", "map", "tick_rate", "perspective", "steamid", "player_slot",
+	"type", "round", "tick_start", "tick_end", "time_start_sec", "time_end_sec",
+	"segment_tick_start", "segment_tick_end", "kills", "kill_ticks", "weapon",
+	"victims", "victim_names", "replay_slots", "score",
+}
+
+func (CSVEncoder) Encode(w io.Writer, result model.HighlightResult) error {
+	keys := make(map[string]bool)
+	for _, h := range result.Highlights {
+		for key := range h.Meta {
+			keys[key] = true
+		}
+	}
+	metaKeys := slices.Sorted(maps.Keys(keys))
+
+	out := csv.NewWriter(w)
+	header := slices.Clone(csvColumns)
+	for _, key := range metaKeys {
+		header = append(header, "meta_"+key)
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, h := range result.Highlights {
+		demo := h.Demo
+		if demo == "" {
+			demo = result.Demo
+		}
+		row := []string{
+			demo, result.Map, formatFloat(result.TickRate), string(result.Perspective), h.SteamID, strconv.Itoa(h.PlayerSlot),
+			string(h.Type), strconv.Itoa(h.Round), strconv.Itoa(h.TickStart), strconv.Itoa(h.TickEnd), formatFloat(h.TimeStart), formatFloat(h.TimeEnd),
+			strconv.Itoa(h.SegmentFrom), strconv.Itoa(h.SegmentTo), strconv.Itoa(h.Kills), joinInts(h.KillTicks), h.Weapon,
+			strings.Join(h.Victims, ";"), strings.Join(h.VictimNames, ";"), joinInts(h.ReplaySlots), formatFloat(h.Score),
+		}
+		for _, key := range metaKeys {
+			row = append(row, h.Meta[key])
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ";")
+}
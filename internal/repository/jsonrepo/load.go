@@ -0,0 +1,62 @@
+package jsonrepo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/repository"
+)
+
+// Load reads a result saved as JSON, migrating older schema versions to
+// the current model.HighlightResult.
+func (r *Repository) Load(ctx context.Context) (model.HighlightResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return model.HighlightResult{}, err
+	}
+	if r.outputPath == "" {
+		return model.HighlightResult{}, errors.New("load: no path")
+	}
+	if r.format != repository.FormatJSON {
+		return model.HighlightResult{}, fmt.Errorf("load %s: only %s results can be loaded, not %s", r.outputPath, repository.FormatJSON, r.format)
+	}
+	data, err := os.ReadFile(r.outputPath)
+	if err != nil {
+		return model.HighlightResult{}, err
+	}
+	result, err := Decode(data)
+	if err != nil {
+		return model.HighlightResult{}, fmt.Errorf("load %s: %w", r.outputPath, err)
+	}
+	return result, nil
+}
+
+// Decode reads a saved JSON result of any schema version up to
+// model.SchemaVersion.
+func Decode(data []byte) (model.HighlightResult, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return model.HighlightResult{}, err
+	}
+	if doc == nil {
+		return model.HighlightResult{}, errors.New("result is not a JSON object")
+	}
+	if err := migrate(doc); err != nil {
+		return model.HighlightResult{}, err
+	}
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return model.HighlightResult{}, err
+	}
+	var result model.HighlightResult
+	if err := json.Unmarshal(migrated, &result); err != nil {
+		return model.HighlightResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package jsonrepo
+
+import (
+	"fmt"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// migrations[v] upgrades a saved result from schema version v to v+1, on
+// the decoded JSON object so fields can be renamed or reshaped.
+var migrations = []func(doc map[string]any) error{
+	migrateUnversioned,
+}
+
+// migrate upgrades doc in place to model.SchemaVersion.
+func migrate(doc map[string]any) error {
+	version := 0
+	if raw, ok := doc["schema_version"]; ok {
+		v, ok := raw.(float64)
+		if !ok || v != float64(int(v)) || v < 0 {
+			return fmt.Errorf("invalid schema_version %v", raw)
+		}
+		version = int(v)
+	}
+	if version > model.SchemaVersion {
+		return fmt.Errorf("schema_version %d is newer than this build reads (%d)", version, model.SchemaVersion)
+	}
+	for ; version < model.SchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return fmt.Errorf("migrate schema_version %d: %w", version, err)
+		}
+		doc["schema_version"] = version + 1
+	}
+	return nil
+}
+
+// migrateUnversioned upgrades results saved before schema_version: they were
+// all kills results when perspective was missing, and early highlights may
+// lack the demo and steamid they now repeat from the result.
+func migrateUnversioned(doc map[string]any) error {
+	if _, ok := doc["perspective"]; !ok {
+		doc["perspective"] = string(model.PerspectiveKills)
+	}
+	highlights, ok := doc["highlights"].([]any)
+	if !ok {
+		if doc["highlights"] != nil {
+			return fmt.Errorf("highlights is not a list")
+		}
+		doc["highlights"] = []any{}
+		return nil
+	}
+	for i, raw := range highlights {
+		h, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("highlight %d is not an object", i)
+		}
+		for _, key := range []string{"demo", "steamid"} {
+			if value, _ := h[key].(string); value == "" && doc[key] != nil {
+				h[key] = doc[key]
+			}
+		}
+	}
+	return nil
+}
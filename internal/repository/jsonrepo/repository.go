@@ -1,21 +1,38 @@
 package jsonrepo
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/eSheikh/cs2-demo-highlighter/internal/atomicfile"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/repository"
 )
 
+// Repository saves a result to one file with its encoder, and loads it
+// back from JSON.
 type Repository struct {
 	outputPath string
+	format     repository.Format
+	encoder    repository.Encoder
 }
 
+// New saves in the format outputPath's extension names, JSON by default.
 func New(outputPath string) *Repository {
-	return &Repository{outputPath: strings.TrimSpace(outputPath)}
+	return NewWithFormat(outputPath, "")
+}
+
+// NewWithFormat saves in format; an empty format is inferred from the
+// extension.
+func NewWithFormat(outputPath string, format repository.Format) *Repository {
+	outputPath = strings.TrimSpace(outputPath)
+	if format == "" {
+		format = repository.FormatForPath(outputPath)
+	}
+	return &Repository{outputPath: outputPath, format: format, encoder: format.Encoder()}
 }
 
 func (r *Repository) Save(ctx context.Context, payload model.HighlightResult) error {
@@ -31,12 +48,13 @@ func (r *Repository) Save(ctx context.Context, payload model.HighlightResult) er
 	if err := os.MkdirAll(filepath.Dir(r.outputPath), 0o755); err != nil {
 		return err
 	}
-	data, err := json.MarshalIndent(payload, "", "  ")
-	if err != nil {
+	payload.SchemaVersion = model.SchemaVersion
+	var data bytes.Buffer
+	if err := r.encoder.Encode(&data, payload); err != nil {
 		return err
 	}
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return os.WriteFile(r.outputPath, data, 0o644)
+	return atomicfile.WriteFile(r.outputPath, data.Bytes(), 0o644)
 }
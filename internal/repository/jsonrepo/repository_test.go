@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/repository"
 )
 
 func TestSaveHonorsCanceledContext(t *testing.T) {
@@ -62,3 +67,140 @@ func TestSaveSkipsWhitespaceOnlyOutputPath(t *testing.T) {
 		t.Fatalf("expected nil error, got %v", err)
 	}
 }
+
+func TestSaveEncodesByExtensionOrFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	payload := model.HighlightResult{Demo: "match.dem", Highlights: []model.Highlight{{Type: model.HighlightHeadshot, Round: 3}}}
+	csvPath := filepath.Join(dir, "result.csv")
+	if err := New(csvPath).Save(context.Background(), payload); err != nil {
+		t.Fatalf("save csv: %v", err)
+	}
+	data, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "demo,map,") || !strings.Contains(string(data), "\nmatch.dem,,0,,,0,headshot_kill,3,") {
+		t.Fatalf("expected csv rows, got:\n%s", data)
+	}
+
+	ndjsonPath := filepath.Join(dir, "result.out")
+	if err := NewWithFormat(ndjsonPath, repository.FormatNDJSON).Save(context.Background(), payload); err != nil {
+		t.Fatalf("save ndjson: %v", err)
+	}
+	data, err = os.ReadFile(ndjsonPath)
+	if err != nil {
+		t.Fatalf("read ndjson: %v", err)
+	}
+	if strings.Count(string(data), "\n") != 1 || !strings.HasPrefix(string(data), `{"type":"headshot_kill"`) {
+		t.Fatalf("expected one highlight line, got:\n%s", data)
+	}
+}
+
+func TestLoadMigratesUnversionedResults(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "old.json")
+	old := `{"demo": "mirage.dem", "steamid": "76561197960265728", "tick_rate": 64, "highlights": [
+		{"type": "headshot_kill", "round": 3, "tick_start": 1000, "tick_end": 1000, "time_start_sec": 15.6, "time_end_sec": 15.6, "steamid": "", "demo": "", "segment_tick_start": 1000, "segment_tick_end": 1000}
+	]}`
+	if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := New(path).Load(context.Background())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.SchemaVersion != model.SchemaVersion || got.Perspective != model.PerspectiveKills {
+		t.Fatalf("expected the result migrated to the current version, got %+v", got)
+	}
+	if h := got.Highlights[0]; h.Demo != "mirage.dem" || h.SteamID != "76561197960265728" || h.Round != 3 {
+		t.Fatalf("expected demo fields filled in, got %+v", h)
+	}
+
+	saved := model.HighlightResult{Demo: "inferno.dem", TickRate: 64, Perspective: model.PerspectiveDeaths, Highlights: []model.Highlight{{Type: model.HighlightDeath, Round: 2, Demo: "inferno.dem", Meta: map[string]string{"killer": "1"}}}}
+	if err := New(path).Save(context.Background(), saved); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err = New(path).Load(context.Background())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	saved.SchemaVersion = model.SchemaVersion
+	if !reflect.DeepEqual(got, saved) {
+		t.Fatalf("expected %+v back, got %+v", saved, got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"schema_version": 99, "highlights": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(path).Load(context.Background()); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("expected a newer version error, got %v", err)
+	}
+	if _, err := New(filepath.Join(t.TempDir(), "result.csv")).Load(context.Background()); err == nil {
+		t.Fatalf("expected csv results to be rejected")
+	}
+}
+
+func TestSchemaDescribesModel(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "..", "schema", "highlights.schema.json"))
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	type object struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Enum       []string                   `json:"enum"`
+	}
+	var schema struct {
+		object
+		Defs map[string]object `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("parse schema: %v", err)
+	}
+
+	for name, value := range map[string]any{
+		"":            model.HighlightResult{},
+		"highlight":   model.Highlight{},
+		"vector":      model.Vector{},
+		"killScene":   model.KillScene{},
+		"teamScore":   model.TeamScore{},
+		"matchScore":  model.MatchScore{},
+		"playerStats": model.PlayerStats{},
+		"statsTotals": model.StatsTotals{},
+		"roundStats":  model.RoundStats{},
+	} {
+		def := schema.object
+		if name != "" {
+			def = schema.Defs[name]
+		}
+		var fields []string
+		typ := reflect.TypeOf(value)
+		for i := range typ.NumField() {
+			fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+		}
+		var properties []string
+		for property := range def.Properties {
+			properties = append(properties, property)
+		}
+		slices.Sort(fields)
+		slices.Sort(properties)
+		if !slices.Equal(fields, properties) {
+			t.Fatalf("schema %q: expected properties %v, got %v", name, fields, properties)
+		}
+	}
+
+	var types []string
+	for _, typ := range model.AllHighlightTypes() {
+		types = append(types, string(typ))
+	}
+	if !slices.Equal(schema.Defs["highlightType"].Enum, types) {
+		t.Fatalf("expected highlight types %v, got %v", types, schema.Defs["highlightType"].Enum)
+	}
+	if version := string(schema.Properties["schema_version"]); version != fmt.Sprintf(`{ "const": %d }`, model.SchemaVersion) {
+		t.Fatalf("expected schema_version %d, got %s", model.SchemaVersion, version)
+	}
+}
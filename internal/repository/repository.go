@@ -0,0 +1,61 @@
+// Package repository defines where extracted results are stored and the
+// encoders that write them: indented JSON, NDJSON with one highlight per
+// line, or CSV for spreadsheets.
+package repository
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// Repository stores the result of one demo, and reads it back migrated to
+// the current model.HighlightResult.
+type Repository interface {
+	Save(ctx context.Context, result model.HighlightResult) error
+	Load(ctx context.Context) (model.HighlightResult, error)
+}
+
+// Encoder writes a result in one output format.
+type Encoder interface {
+	Encode(w io.Writer, result model.HighlightResult) error
+}
+
+// Format is an output format.
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+func AllFormats() []Format {
+	return []Format{FormatJSON, FormatNDJSON, FormatCSV}
+}
+
+// Encoder returns the encoder of f; unknown formats encode as JSON.
+func (f Format) Encoder() Encoder {
+	switch f {
+	case FormatNDJSON:
+		return NDJSONEncoder{}
+	case FormatCSV:
+		return CSVEncoder{}
+	}
+	return JSONEncoder{}
+}
+
+// FormatForPath infers the format from path's extension: .ndjson and .jsonl
+// are NDJSON, .csv is CSV, anything else JSON.
+func FormatForPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".csv":
+		return FormatCSV
+	}
+	return FormatJSON
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+func testResult() model.HighlightResult {
+	return model.HighlightResult{
+		Demo:        "mirage.dem",
+		SteamID:     "76561197960265728",
+		TickRate:    64,
+		Map:         "de_mirage",
+		Score:       &model.MatchScore{CT: model.TeamScore{Score: 13}, T: model.TeamScore{Score: 9}, Side: "CT"},
+		Perspective: model.PerspectiveKills,
+		Highlights: []model.Highlight{
+			{Type: model.HighlightClutchWin, Round: 14, TickStart: 9000, TickEnd: 9500, Kills: 2, KillTicks: []int{9000, 9500}, Victims: []string{"a", "b"}, VictimNames: []string{"x, y", "z"}, Meta: map[string]string{"clutch": "1v2"}, Weapon: "AK-47", SteamID: "76561197960265728", Demo: "mirage.dem", TimeStart: 140.625},
+			{Type: model.HighlightHeadshot, Round: 3, TickStart: 1000, TickEnd: 1000, Victims: []string{"c"}, Meta: map[string]string{"hp": "3"}, SteamID: "76561197960265728"},
+		},
+	}
+}
+
+func TestNDJSONDenormalizesDemoFields(t *testing.T) {
+	var out bytes.Buffer
+	if err := FormatNDJSON.Encoder().Encode(&out, testResult()); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a line per highlight, got:\n%s", out.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("decode line: %v", err)
+	}
+	if record["type"] != "headshot_kill" || record["demo"] != "mirage.dem" || record["map"] != "de_mirage" || record["tick_rate"] != 64.0 || record["perspective"] != "kills" {
+		t.Fatalf("expected demo fields on the line, got %v", record)
+	}
+	if score, ok := record["match_score"].(map[string]any); !ok || score["side"] != "CT" {
+		t.Fatalf("expected the match score, got %v", record["match_score"])
+	}
+}
+
+func TestCSVFlattensMetaAndVictims(t *testing.T) {
+	var out bytes.Buffer
+	if err := FormatCSV.Encoder().Encode(&out, testResult()); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected a header and two rows, got %v", rows)
+	}
+	header := rows[0]
+	if got := strings.Join(header[len(header)-2:], ","); got != "meta_clutch,meta_hp" {
+		t.Fatalf("expected sorted meta columns, got %s", got)
+	}
+	column := func(row []string, name string) string {
+		for i, h := range header {
+			if h == name {
+				return row[i]
+			}
+		}
+		t.Fatalf("no column %s", name)
+		return ""
+	}
+	clutch, headshot := rows[1], rows[2]
+	if column(clutch, "victims") != "a;b" || column(clutch, "victim_names") != "x, y;z" || column(clutch, "kill_ticks") != "9000;9500" || column(clutch, "time_start_sec") != "140.625" {
+		t.Fatalf("unexpected clutch row: %v", clutch)
+	}
+	if column(clutch, "meta_clutch") != "1v2" || column(clutch, "meta_hp") != "" || column(headshot, "meta_hp") != "3" || column(headshot, "demo") != "mirage.dem" {
+		t.Fatalf("unexpected meta columns: %v / %v", clutch, headshot)
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	for path, want := range map[string]Format{
+		"out/result.json":  FormatJSON,
+		"result.NDJSON":    FormatNDJSON,
+		"result.jsonl":     FormatNDJSON,
+		"C:/out/stats.csv": FormatCSV,
+		"result":           FormatJSON,
+	} {
+		if got := FormatForPath(path); got != want {
+			t.Fatalf("%s: expected %s, got %s", path, want, got)
+		}
+	}
+}
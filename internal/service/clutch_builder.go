@@ -95,10 +95,14 @@ func newClutchWinHighlight(demo string, steamID string, maxEnemies int, clutchKi
 		TimeStart: first.Time.Seconds(),
 		TimeEnd:   last.Time.Seconds(),
 		Kills:     len(clutchKills),
+		KillTicks: collectKillTicks(clutchKills),
 		Meta: map[string]string{
 			"clutch": fmt.Sprintf("1v%d", maxEnemies),
 		},
 		Victims:     collectVictims(clutchKills),
+		VictimNames: collectVictimNames(clutchKills),
+		ReplaySlots: collectVictimSlots(clutchKills),
+		Scene:       first.Scene,
 		Weapon:      last.Weapon,
 		PlayerSlot:  first.KillerSlot,
 		SteamID:     steamID,
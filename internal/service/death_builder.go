@@ -0,0 +1,23 @@
+package service
+
+import "github.com/eSheikh/cs2-demo-highlighter/internal/model"
+
+// BuildDeathHighlights turns the player's deaths into highlight entries for a
+// coaching reel. POV stays on the player (the victim) so the recording shows
+// what they saw before dying.
+func (s *HighlightService) BuildDeathHighlights(parsed model.ParsedDemo, steamID string, selection model.Selection) model.HighlightResult {
+	items := make([]model.Highlight, 0, len(parsed.Deaths))
+	for _, death := range parsed.Deaths {
+		items = append(items, newDeathHighlight(parsed.Demo, steamID, death))
+	}
+
+	return model.HighlightResult{
+		Demo:        parsed.Demo,
+		SteamID:     steamID,
+		TickRate:    parsed.TickRate,
+		Map:         parsed.Map,
+		Score:       parsed.Score,
+		Perspective: model.PerspectiveDeaths,
+		Highlights:  filterBySelection(items, selection),
+	}
+}
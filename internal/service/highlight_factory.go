@@ -1,6 +1,11 @@
 package service
 
-import "github.com/eSheikh/cs2-demo-highlighter/internal/model"
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
 
 func newSingleKillHighlight(demo string, steamID string, kill model.KillEvent, highlightType model.HighlightType) model.Highlight {
 	return model.Highlight{
@@ -11,6 +16,9 @@ func newSingleKillHighlight(demo string, steamID string, kill model.KillEvent, h
 		TimeStart:   kill.Time.Seconds(),
 		TimeEnd:     kill.Time.Seconds(),
 		Victims:     []string{kill.VictimID},
+		VictimNames: []string{kill.VictimName},
+		ReplaySlots: []int{kill.VictimSlot},
+		Scene:       kill.Scene,
 		Weapon:      kill.Weapon,
 		PlayerSlot:  kill.KillerSlot,
 		SteamID:     steamID,
@@ -34,6 +42,9 @@ func newMultiKillHighlight(demo string, steamID string, kills []model.KillEvent)
 		Kills:       len(kills),
 		KillTicks:   collectKillTicks(kills),
 		Victims:     collectVictims(kills),
+		VictimNames: collectVictimNames(kills),
+		ReplaySlots: collectVictimSlots(kills),
+		Scene:       first.Scene,
 		Weapon:      last.Weapon,
 		PlayerSlot:  first.KillerSlot,
 		SteamID:     steamID,
@@ -43,6 +54,36 @@ func newMultiKillHighlight(demo string, steamID string, kills []model.KillEvent)
 	}
 }
 
+// newDeathHighlight describes a death from the victim's side: alive counts are
+// "victim team v killer team" before the kill.
+func newDeathHighlight(demo string, steamID string, death model.KillEvent) model.Highlight {
+	return model.Highlight{
+		Type:      model.HighlightDeath,
+		Round:     death.Round,
+		TickStart: death.Tick,
+		TickEnd:   death.Tick,
+		TimeStart: death.Time.Seconds(),
+		TimeEnd:   death.Time.Seconds(),
+		Meta: map[string]string{
+			"killer":      death.KillerID,
+			"killer_name": death.KillerName,
+			"traded":      strconv.FormatBool(death.Traded),
+			"flashed":     strconv.FormatBool(death.VictimBlinded),
+			"alive":       fmt.Sprintf("%dv%d", death.EnemiesAliveBefore, death.AlliesAliveBefore),
+		},
+		Victims:     []string{death.VictimID},
+		VictimNames: []string{death.VictimName},
+		ReplaySlots: []int{death.KillerSlot},
+		Scene:       death.Scene,
+		Weapon:      death.Weapon,
+		PlayerSlot:  death.VictimSlot,
+		SteamID:     steamID,
+		Demo:        demo,
+		SegmentFrom: death.Tick,
+		SegmentTo:   death.Tick,
+	}
+}
+
 func collectVictims(kills []model.KillEvent) []string {
 	victims := make([]string, 0, len(kills))
 	for _, kill := range kills {
@@ -51,6 +92,22 @@ func collectVictims(kills []model.KillEvent) []string {
 	return victims
 }
 
+func collectVictimNames(kills []model.KillEvent) []string {
+	names := make([]string, 0, len(kills))
+	for _, kill := range kills {
+		names = append(names, kill.VictimName)
+	}
+	return names
+}
+
+func collectVictimSlots(kills []model.KillEvent) []int {
+	slots := make([]int, 0, len(kills))
+	for _, kill := range kills {
+		slots = append(slots, kill.VictimSlot)
+	}
+	return slots
+}
+
 func collectKillTicks(kills []model.KillEvent) []int {
 	ticks := make([]int, 0, len(kills))
 	for _, kill := range kills {
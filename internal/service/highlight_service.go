@@ -6,24 +6,39 @@ import (
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
 )
 
-type HighlightService struct{}
+// HighlightService turns parsed events into highlights. StreakRules configures
+// the cross-round kill_streak detector; LowHPThreshold is the highest killer
+// health that still counts as a low_hp_kill.
+type HighlightService struct {
+	StreakRules    []StreakRule
+	LowHPThreshold int
+}
 
 func NewHighlightService() *HighlightService {
-	return &HighlightService{}
+	return &HighlightService{
+		StreakRules:    DefaultStreakRules(),
+		LowHPThreshold: defaultLowHPThreshold,
+	}
 }
 
-func (s *HighlightService) BuildHighlights(demo string, steamID string, tickRate float64, kills []model.KillEvent, selection model.Selection) model.HighlightResult {
+func (s *HighlightService) BuildHighlights(parsed model.ParsedDemo, steamID string, selection model.Selection) model.HighlightResult {
+	demo, kills := parsed.Demo, parsed.Kills
 	highlights := slices.Concat(
 		s.buildSingleKillHighlights(demo, steamID, kills),
+		s.buildLowHPKillHighlights(demo, steamID, kills),
 		s.buildMultiKillHighlights(demo, steamID, kills),
 		s.buildClutchWinHighlights(demo, steamID, kills),
+		s.buildKillStreakHighlights(demo, steamID, kills, parsed.Deaths),
 	)
 
 	return model.HighlightResult{
-		Demo:       demo,
-		SteamID:    steamID,
-		TickRate:   tickRate,
-		Highlights: filterBySelection(highlights, selection),
+		Demo:        demo,
+		SteamID:     steamID,
+		TickRate:    parsed.TickRate,
+		Map:         parsed.Map,
+		Score:       parsed.Score,
+		Perspective: model.PerspectiveKills,
+		Highlights:  filterBySelection(highlights, selection),
 	}
 }
 
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
 )
 
@@ -30,7 +34,7 @@ func TestBuildHighlightsBuildsSingleAndMultiKill(t *testing.T) {
 		},
 	}
 
-	result := svc.BuildHighlights("match.dem", "7656119", 64, kills, nil)
+	result := svc.BuildHighlights(model.ParsedDemo{Demo: "match.dem", TickRate: 64, Kills: kills}, "7656119", nil)
 
 	if result.Demo != "match.dem" || result.SteamID != "7656119" || result.TickRate != 64 {
 		t.Fatalf("unexpected metadata: %+v", result)
@@ -56,7 +60,7 @@ func TestBuildHighlightsAppliesSelection(t *testing.T) {
 		{Tick: 120, Round: 1, VictimID: "v2", KillerSlot: 7, IsNoScope: true},
 	}
 
-	result := svc.BuildHighlights("match.dem", "s", 64, kills, model.Selection{model.HighlightMultiKill: true})
+	result := svc.BuildHighlights(model.ParsedDemo{Demo: "match.dem", TickRate: 64, Kills: kills}, "s", model.Selection{model.HighlightMultiKill: true})
 
 	if len(result.Highlights) != 1 {
 		t.Fatalf("expected only multikill after selection, got %d", len(result.Highlights))
@@ -97,6 +101,7 @@ func TestBuildClutchWinHighlights(t *testing.T) {
 			Time:               30 * time.Second,
 			Round:              7,
 			VictimID:           "v1",
+			VictimSlot:         1,
 			Weapon:             "ak47",
 			KillerSlot:         9,
 			AlliesAliveBefore:  2,
@@ -108,6 +113,7 @@ func TestBuildClutchWinHighlights(t *testing.T) {
 			Time:               31 * time.Second,
 			Round:              7,
 			VictimID:           "v2",
+			VictimSlot:         2,
 			Weapon:             "ak47",
 			KillerSlot:         9,
 			AlliesAliveBefore:  1,
@@ -119,6 +125,7 @@ func TestBuildClutchWinHighlights(t *testing.T) {
 			Time:               32 * time.Second,
 			Round:              7,
 			VictimID:           "v3",
+			VictimSlot:         3,
 			Weapon:             "ak47",
 			KillerSlot:         9,
 			AlliesAliveBefore:  1,
@@ -130,6 +137,7 @@ func TestBuildClutchWinHighlights(t *testing.T) {
 			Time:               33 * time.Second,
 			Round:              7,
 			VictimID:           "v4",
+			VictimSlot:         4,
 			Weapon:             "ak47",
 			KillerSlot:         9,
 			AlliesAliveBefore:  1,
@@ -156,4 +164,235 @@ func TestBuildClutchWinHighlights(t *testing.T) {
 	if highlight.Kills != 3 {
 		t.Fatalf("expected 3 kills in clutch sequence, got %d", highlight.Kills)
 	}
+	if !reflect.DeepEqual(highlight.KillTicks, []int{540, 580, 620}) || !reflect.DeepEqual(highlight.ReplaySlots, []int{2, 3, 4}) {
+		t.Fatalf("expected a kill tick per replay slot, got %v and %v", highlight.KillTicks, highlight.ReplaySlots)
+	}
+
+	// Each kill of the clutch is replayed from its victim's POV.
+	result := model.HighlightResult{TickRate: 64, Highlights: highlights}
+	files, err := hlae.BuildTarget(result, hlae.Options{PreRollSeconds: 1, PostRollSeconds: 1, ReplaySeconds: 1}, hlae.Target{Mode: hlae.ModeClips, Path: "clutch.cfg", Name: "clutch"})
+	if err != nil {
+		t.Fatalf("build target: %v", err)
+	}
+	for _, slot := range highlight.ReplaySlots {
+		if want := fmt.Sprintf("spec_player %d; demo_resume", slot); !strings.Contains(files[0].Content, want) {
+			t.Fatalf("expected a replay from slot %d:\n%s", slot, files[0].Content)
+		}
+	}
+}
+
+func TestBuildDeathHighlightsLocksPovOnVictim(t *testing.T) {
+	svc := NewHighlightService()
+	parsed := model.ParsedDemo{
+		Demo:     "match.dem",
+		TickRate: 64,
+		Deaths: []model.KillEvent{
+			{
+				Tick:               900,
+				Time:               40 * time.Second,
+				Round:              4,
+				KillerID:           "k1",
+				KillerName:         "enemy",
+				KillerSlot:         3,
+				VictimID:           "steam",
+				VictimSlot:         9,
+				Weapon:             "awp",
+				VictimBlinded:      true,
+				Traded:             true,
+				AlliesAliveBefore:  4,
+				EnemiesAliveBefore: 2,
+			},
+		},
+	}
+
+	result := svc.BuildDeathHighlights(parsed, "steam", nil)
+	if result.Perspective != model.PerspectiveDeaths {
+		t.Fatalf("expected deaths perspective, got %q", result.Perspective)
+	}
+	if len(result.Highlights) != 1 {
+		t.Fatalf("expected 1 death highlight, got %d", len(result.Highlights))
+	}
+
+	death := result.Highlights[0]
+	if death.Type != model.HighlightDeath || death.PlayerSlot != 9 {
+		t.Fatalf("expected death locked on victim slot 9, got %+v", death)
+	}
+	if len(death.ReplaySlots) != 1 || death.ReplaySlots[0] != 3 {
+		t.Fatalf("expected killcam replay from killer slot 3, got %v", death.ReplaySlots)
+	}
+	if death.Meta["killer"] != "k1" || death.Meta["killer_name"] != "enemy" {
+		t.Fatalf("unexpected killer meta: %+v", death.Meta)
+	}
+	if death.Meta["traded"] != "true" || death.Meta["flashed"] != "true" {
+		t.Fatalf("unexpected trade/flash meta: %+v", death.Meta)
+	}
+	if death.Meta["alive"] != "2v4" {
+		t.Fatalf("expected alive 2v4 from the victim's side, got %q", death.Meta["alive"])
+	}
+}
+
+func TestBuildKillStreakHighlights(t *testing.T) {
+	svc := NewHighlightService()
+	svc.StreakRules = []StreakRule{
+		{Kind: StreakRounds, Min: 3},
+		{Kind: StreakKills, Min: 4},
+	}
+
+	kills := []model.KillEvent{
+		{Tick: 100, Round: 1, VictimID: "v1", KillerSlot: 3},
+		{Tick: 200, Round: 2, VictimID: "v2", KillerSlot: 3},
+		{Tick: 210, Round: 2, VictimID: "v3", KillerSlot: 3},
+		{Tick: 300, Round: 3, VictimID: "v4", KillerSlot: 3},
+		{Tick: 500, Round: 5, VictimID: "v5", KillerSlot: 3},
+	}
+	deaths := []model.KillEvent{{Tick: 250, Round: 2, VictimID: "steam"}}
+
+	highlights := svc.buildKillStreakHighlights("match.dem", "steam", kills, deaths)
+	if len(highlights) != 1 {
+		t.Fatalf("expected only the 3-round streak (death breaks the kills streak), got %+v", highlights)
+	}
+
+	streak := highlights[0]
+	if streak.Type != model.HighlightKillStreak || streak.Meta["streak"] != "3 rounds" {
+		t.Fatalf("unexpected streak highlight: %+v", streak)
+	}
+	if streak.Meta["rounds"] != "1-3" || streak.Kills != 4 {
+		t.Fatalf("unexpected streak span: %+v", streak)
+	}
+	if len(streak.KillTicks) != 4 || streak.KillTicks[3] != 300 {
+		t.Fatalf("expected constituent kill ticks, got %v", streak.KillTicks)
+	}
+}
+
+func TestKillsWithoutDyingRunsSplitsAtDeaths(t *testing.T) {
+	kills := []model.KillEvent{{Tick: 10}, {Tick: 20}, {Tick: 40}, {Tick: 50}, {Tick: 60}}
+	deaths := []model.KillEvent{{Tick: 30}, {Tick: 35}}
+
+	runs := killsWithoutDyingRuns(kills, deaths)
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	if len(runs[0]) != 2 || len(runs[1]) != 3 {
+		t.Fatalf("unexpected run sizes: %d, %d", len(runs[0]), len(runs[1]))
+	}
+}
+
+func TestBuildLowHPKillHighlights(t *testing.T) {
+	svc := NewHighlightService()
+	svc.LowHPThreshold = 5
+
+	kills := []model.KillEvent{
+		{Tick: 100, Round: 2, VictimID: "v1", KillerSlot: 4, KillerHealth: 3, KillerArmor: 0, DamageTaken: 97},
+		{Tick: 200, Round: 3, VictimID: "v2", KillerSlot: 4, KillerHealth: 40, KillerArmor: 100},
+		{Tick: 300, Round: 4, VictimID: "v3", KillerSlot: 4, KillerHealth: 0},
+	}
+
+	highlights := svc.buildLowHPKillHighlights("match.dem", "steam", kills)
+	if len(highlights) != 1 {
+		t.Fatalf("expected 1 low hp kill, got %d", len(highlights))
+	}
+	h := highlights[0]
+	if h.Type != model.HighlightLowHPKill || h.SegmentFrom != 100 {
+		t.Fatalf("unexpected low hp highlight: %+v", h)
+	}
+	if h.Meta["hp"] != "3" || h.Meta["armor"] != "0" || h.Meta["damage_taken"] != "97" {
+		t.Fatalf("unexpected low hp meta: %+v", h.Meta)
+	}
+
+	svc.LowHPThreshold = 0
+	if got := svc.buildLowHPKillHighlights("match.dem", "steam", kills); len(got) != 0 {
+		t.Fatalf("expected threshold 0 to disable the detector, got %d", len(got))
+	}
+}
+
+func TestBuildTeamHighlightsRanksAcrossPlayers(t *testing.T) {
+	svc := NewHighlightService()
+	parsed := model.ParsedDemo{
+		Demo:     "match.dem",
+		TickRate: 64,
+		Kills: []model.KillEvent{
+			{Tick: 100, Round: 1, KillerID: "a", KillerSlot: 2, VictimID: "x1", IsHeadshot: true},
+			{Tick: 300, Round: 2, KillerID: "b", KillerSlot: 6, VictimID: "y1"},
+			{Tick: 320, Round: 2, KillerID: "b", KillerSlot: 6, VictimID: "y2"},
+			{Tick: 340, Round: 2, KillerID: "b", KillerSlot: 6, VictimID: "y3"},
+		},
+	}
+
+	result := svc.BuildTeamHighlights(parsed, nil, 0)
+	if result.SteamID != "" || len(result.Highlights) != 2 {
+		t.Fatalf("expected headshot + multikill across players, got %+v", result)
+	}
+
+	best := result.Highlights[0]
+	if best.Type != model.HighlightMultiKill || best.SteamID != "b" || best.PlayerSlot != 6 {
+		t.Fatalf("expected b's 3k ranked first, got %+v", best)
+	}
+	if result.Highlights[1].SteamID != "a" || result.Highlights[1].PlayerSlot != 2 {
+		t.Fatalf("expected a's headshot to keep its own player, got %+v", result.Highlights[1])
+	}
+	if best.Score <= result.Highlights[1].Score {
+		t.Fatalf("expected descending scores, got %v then %v", best.Score, result.Highlights[1].Score)
+	}
+
+	if top := svc.BuildTeamHighlights(parsed, nil, 1); len(top.Highlights) != 1 || top.Highlights[0].SteamID != "b" {
+		t.Fatalf("expected top 1 to keep the best highlight, got %+v", top.Highlights)
+	}
+}
+
+func TestScoreHighlightClutchScalesWithEnemies(t *testing.T) {
+	oneVsTwo := scoreHighlight(model.Highlight{Type: model.HighlightClutchWin, Meta: map[string]string{"clutch": "1v2"}})
+	oneVsFour := scoreHighlight(model.Highlight{Type: model.HighlightClutchWin, Meta: map[string]string{"clutch": "1v4"}})
+	if oneVsFour <= oneVsTwo {
+		t.Fatalf("expected 1v4 to outscore 1v2, got %v <= %v", oneVsFour, oneVsTwo)
+	}
+}
+
+func TestBuildStatsAggregatesRoundsAndTotals(t *testing.T) {
+	svc := NewHighlightService()
+	parsed := model.ParsedDemo{
+		Kills: []model.KillEvent{
+			{Round: 1, KillerID: "me", IsHeadshot: true, IsOpening: true},
+			{Round: 1, KillerID: "me"},
+			{Round: 3, KillerID: "me", IsHeadshot: true},
+		},
+		Deaths: []model.KillEvent{
+			{Round: 2, VictimID: "me", IsOpening: true},
+			{Round: 3, VictimID: "me", Traded: true},
+			{Round: 4, VictimID: "me"},
+		},
+		Assists: []model.KillEvent{{Round: 4, AssisterID: "me"}},
+		Damage: []model.DamageEvent{
+			{Round: 1, AttackerID: "me", Damage: 200},
+			{Round: 3, AttackerID: "me", Damage: 100},
+			{Round: 4, AttackerID: "me", Damage: 100},
+		},
+		Rounds: []model.RoundInfo{
+			{Number: 1, Winner: "CT"},
+			{Number: 2, Winner: "T"},
+			{Number: 3, Winner: "CT", ClutchEnemies: 2, ClutchWon: true},
+			{Number: 4, Winner: "T"},
+		},
+	}
+
+	stats := svc.BuildStats(parsed, "me")
+	if len(stats.Rounds) != 4 {
+		t.Fatalf("expected 4 rounds, got %d", len(stats.Rounds))
+	}
+	if stats.Rounds[1].KAST {
+		t.Fatalf("round 2 death without kill/assist/trade must not count for KAST")
+	}
+	if !stats.Rounds[2].KAST || stats.Rounds[2].Clutch != "1v2" || !stats.Rounds[2].ClutchWon {
+		t.Fatalf("unexpected round 3 stats: %+v", stats.Rounds[2])
+	}
+
+	totals := stats.Totals
+	if totals.Kills != 3 || totals.Deaths != 3 || totals.Assists != 1 || totals.Damage != 400 {
+		t.Fatalf("unexpected totals: %+v", totals)
+	}
+	if totals.ADR != 100 || totals.HSPercent != 66.7 || totals.KASTPercent != 75 {
+		t.Fatalf("unexpected ratios: adr=%v hs=%v kast=%v", totals.ADR, totals.HSPercent, totals.KASTPercent)
+	}
+	if totals.OpeningKills != 1 || totals.OpeningDeaths != 1 || totals.ClutchesWon != 1 || totals.ClutchesAttempted != 1 {
+		t.Fatalf("unexpected opening/clutch totals: %+v", totals)
+	}
 }
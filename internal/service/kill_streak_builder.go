@@ -0,0 +1,144 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// StreakKind names how a kill streak is measured across rounds.
+type StreakKind string
+
+const (
+	// StreakRounds counts consecutive rounds with at least one kill.
+	StreakRounds StreakKind = "rounds"
+	// StreakKills counts kills between two deaths of the player.
+	StreakKills StreakKind = "kills"
+)
+
+// StreakRule emits a kill_streak highlight once a streak of Kind reaches Min.
+type StreakRule struct {
+	Kind StreakKind
+	Min  int
+}
+
+func DefaultStreakRules() []StreakRule {
+	return []StreakRule{
+		{Kind: StreakRounds, Min: 5},
+		{Kind: StreakKills, Min: 10},
+	}
+}
+
+func (s *HighlightService) buildKillStreakHighlights(demo string, steamID string, kills []model.KillEvent, deaths []model.KillEvent) []model.Highlight {
+	items := make([]model.Highlight, 0)
+	for _, rule := range s.StreakRules {
+		if rule.Min < 2 {
+			continue
+		}
+		for _, run := range streakRuns(rule.Kind, kills, deaths) {
+			length := streakLength(rule.Kind, run)
+			if length < rule.Min {
+				continue
+			}
+			items = append(items, newKillStreakHighlight(demo, steamID, rule.Kind, length, run))
+		}
+	}
+	return items
+}
+
+func streakRuns(kind StreakKind, kills []model.KillEvent, deaths []model.KillEvent) [][]model.KillEvent {
+	switch kind {
+	case StreakRounds:
+		return consecutiveRoundRuns(kills)
+	case StreakKills:
+		return killsWithoutDyingRuns(kills, deaths)
+	default:
+		return nil
+	}
+}
+
+func streakLength(kind StreakKind, run []model.KillEvent) int {
+	if kind == StreakRounds {
+		return run[len(run)-1].Round - run[0].Round + 1
+	}
+	return len(run)
+}
+
+// consecutiveRoundRuns splits kills wherever a round without a kill breaks the
+// sequence.
+func consecutiveRoundRuns(kills []model.KillEvent) [][]model.KillEvent {
+	runs := make([][]model.KillEvent, 0)
+	start := 0
+	for i := 1; i <= len(kills); i++ {
+		if i < len(kills) && kills[i].Round-kills[i-1].Round <= 1 {
+			continue
+		}
+		if i > start {
+			runs = append(runs, kills[start:i])
+		}
+		start = i
+	}
+	return runs
+}
+
+// killsWithoutDyingRuns splits kills at every death of the player.
+func killsWithoutDyingRuns(kills []model.KillEvent, deaths []model.KillEvent) [][]model.KillEvent {
+	deathTicks := make([]int, 0, len(deaths))
+	for _, death := range deaths {
+		deathTicks = append(deathTicks, death.Tick)
+	}
+	sort.Ints(deathTicks)
+
+	runs := make([][]model.KillEvent, 0)
+	start := 0
+	next := 0
+	for i, kill := range kills {
+		died := false
+		for next < len(deathTicks) && deathTicks[next] < kill.Tick {
+			died = true
+			next++
+		}
+		if !died {
+			continue
+		}
+		if i > start {
+			runs = append(runs, kills[start:i])
+		}
+		start = i
+	}
+	if start < len(kills) {
+		runs = append(runs, kills[start:])
+	}
+	return runs
+}
+
+func newKillStreakHighlight(demo string, steamID string, kind StreakKind, length int, run []model.KillEvent) model.Highlight {
+	first := run[0]
+	last := run[len(run)-1]
+
+	return model.Highlight{
+		Type:      model.HighlightKillStreak,
+		Round:     first.Round,
+		TickStart: first.Tick,
+		TickEnd:   last.Tick,
+		TimeStart: first.Time.Seconds(),
+		TimeEnd:   last.Time.Seconds(),
+		Kills:     len(run),
+		KillTicks: collectKillTicks(run),
+		Meta: map[string]string{
+			"streak": fmt.Sprintf("%d %s", length, kind),
+			"rounds": fmt.Sprintf("%d-%d", first.Round, last.Round),
+		},
+		Victims:     collectVictims(run),
+		VictimNames: collectVictimNames(run),
+		ReplaySlots: collectVictimSlots(run),
+		Scene:       first.Scene,
+		Weapon:      last.Weapon,
+		PlayerSlot:  first.KillerSlot,
+		SteamID:     steamID,
+		Demo:        demo,
+		SegmentFrom: first.Tick,
+		SegmentTo:   last.Tick,
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+const defaultLowHPThreshold = 10
+
+// buildLowHPKillHighlights keeps kills where the player survived the duel on
+// LowHPThreshold health or less. A threshold of 0 disables the detector.
+func (s *HighlightService) buildLowHPKillHighlights(demo string, steamID string, kills []model.KillEvent) []model.Highlight {
+	if s.LowHPThreshold <= 0 {
+		return nil
+	}
+
+	items := make([]model.Highlight, 0)
+	for _, kill := range kills {
+		if kill.KillerHealth <= 0 || kill.KillerHealth > s.LowHPThreshold {
+			continue
+		}
+		highlight := newSingleKillHighlight(demo, steamID, kill, model.HighlightLowHPKill)
+		highlight.Meta = map[string]string{
+			"hp":           strconv.Itoa(kill.KillerHealth),
+			"armor":        strconv.Itoa(kill.KillerArmor),
+			"damage_taken": strconv.Itoa(kill.DamageTaken),
+		}
+		items = append(items, highlight)
+	}
+	return items
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+var baseScores = map[model.HighlightType]float64{
+	model.HighlightHeadshot:    3,
+	model.HighlightKillBlinded: 8,
+	model.HighlightKillInSmoke: 8,
+	model.HighlightWallbang:    8,
+	model.HighlightNoScope:     10,
+	model.HighlightLowHPKill:   12,
+	model.HighlightMultiKill:   0,
+	model.HighlightKillStreak:  30,
+	model.HighlightClutchWin:   50,
+}
+
+// scoreHighlight rates how watchable a highlight is, so plays of different
+// types and players can be ranked in one reel. Multikills scale with kill
+// count (an ace gets a bonus) and clutches with the number of enemies.
+func scoreHighlight(h model.Highlight) float64 {
+	score := baseScores[h.Type]
+	switch h.Type {
+	case model.HighlightMultiKill:
+		score += 10 * float64(h.Kills)
+		if h.Kills >= 5 {
+			score += 20
+		}
+	case model.HighlightKillStreak:
+		score += 2 * float64(h.Kills)
+	case model.HighlightClutchWin:
+		score += 10 * float64(clutchEnemies(h.Meta["clutch"]))
+	}
+	return score
+}
+
+// clutchEnemies reads N from a "1vN" clutch label.
+func clutchEnemies(label string) int {
+	_, enemies, ok := strings.Cut(label, "v")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(enemies)
+	if err != nil {
+		return 0
+	}
+	return n
+}
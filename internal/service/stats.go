@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// BuildStats aggregates the player's scoreboard from the same parse the
+// highlights come from: per-round lines plus match totals (ADR, HS%, KAST,
+// opening duels, clutches).
+func (s *HighlightService) BuildStats(parsed model.ParsedDemo, steamID string) *model.PlayerStats {
+	byRound := make(map[int]*model.RoundStats)
+	roundFor := func(number int) *model.RoundStats {
+		if existing := byRound[number]; existing != nil {
+			return existing
+		}
+		round := &model.RoundStats{Round: number}
+		byRound[number] = round
+		return round
+	}
+
+	for _, info := range parsed.Rounds {
+		round := roundFor(info.Number)
+		if info.ClutchEnemies > 0 {
+			round.Clutch = fmt.Sprintf("1v%d", info.ClutchEnemies)
+			round.ClutchWon = info.ClutchWon
+		}
+	}
+
+	traded := make(map[int]bool)
+	for _, kill := range parsed.Kills {
+		if kill.KillerID != steamID {
+			continue
+		}
+		round := roundFor(kill.Round)
+		round.Kills++
+		if kill.IsHeadshot {
+			round.Headshots++
+		}
+		round.OpeningKill = round.OpeningKill || kill.IsOpening
+	}
+	for _, death := range parsed.Deaths {
+		if death.VictimID != steamID {
+			continue
+		}
+		round := roundFor(death.Round)
+		round.Deaths++
+		round.OpeningDeath = round.OpeningDeath || death.IsOpening
+		traded[death.Round] = traded[death.Round] || death.Traded
+	}
+	for _, assist := range parsed.Assists {
+		if assist.AssisterID == steamID {
+			roundFor(assist.Round).Assists++
+		}
+	}
+	for _, damage := range parsed.Damage {
+		if damage.AttackerID == steamID {
+			roundFor(damage.Round).Damage += damage.Damage
+		}
+	}
+
+	numbers := make([]int, 0, len(byRound))
+	for number := range byRound {
+		if number > 0 {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+
+	stats := &model.PlayerStats{Rounds: make([]model.RoundStats, 0, len(numbers))}
+	for _, number := range numbers {
+		round := byRound[number]
+		round.KAST = round.Kills > 0 || round.Assists > 0 || round.Deaths == 0 || traded[number]
+		stats.Rounds = append(stats.Rounds, *round)
+	}
+	stats.Totals = sumRoundStats(stats.Rounds)
+	return stats
+}
+
+func sumRoundStats(rounds []model.RoundStats) model.StatsTotals {
+	totals := model.StatsTotals{Rounds: len(rounds)}
+	kastRounds := 0
+	for _, round := range rounds {
+		totals.Kills += round.Kills
+		totals.Deaths += round.Deaths
+		totals.Assists += round.Assists
+		totals.Damage += round.Damage
+		totals.Headshots += round.Headshots
+		if round.KAST {
+			kastRounds++
+		}
+		if round.OpeningKill {
+			totals.OpeningKills++
+		}
+		if round.OpeningDeath {
+			totals.OpeningDeaths++
+		}
+		if round.Clutch != "" {
+			totals.ClutchesAttempted++
+		}
+		if round.ClutchWon {
+			totals.ClutchesWon++
+		}
+	}
+
+	totals.ADR = ratio(totals.Damage, totals.Rounds, 1)
+	totals.HSPercent = ratio(totals.Headshots, totals.Kills, 100)
+	totals.KASTPercent = ratio(kastRounds, totals.Rounds, 100)
+	return totals
+}
+
+// ratio returns part/whole*scale rounded to one decimal, or 0 for an empty
+// whole.
+func ratio(part int, whole int, scale float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(whole)*scale*10) / 10
+}
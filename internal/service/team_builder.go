@@ -0,0 +1,76 @@
+package service
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+// BuildTeamHighlights extracts highlights for every player who got a kill and
+// ranks them in one list, best first. parsed must hold every player's kills
+// and deaths (a parse with an empty steamID). top > 0 keeps only the best top
+// highlights.
+func (s *HighlightService) BuildTeamHighlights(parsed model.ParsedDemo, selection model.Selection, top int) model.HighlightResult {
+	highlights := make([]model.Highlight, 0)
+	for _, steamID := range killerIDs(parsed.Kills) {
+		player := model.ParsedDemo{
+			Demo:     parsed.Demo,
+			TickRate: parsed.TickRate,
+			Kills:    filterKills(parsed.Kills, func(kill model.KillEvent) bool { return kill.KillerID == steamID }),
+			Deaths:   filterKills(parsed.Deaths, func(kill model.KillEvent) bool { return kill.VictimID == steamID }),
+		}
+		highlights = append(highlights, s.BuildHighlights(player, steamID, selection).Highlights...)
+	}
+
+	rankHighlights(highlights)
+	if top > 0 && len(highlights) > top {
+		highlights = highlights[:top]
+	}
+
+	return model.HighlightResult{
+		Demo:        parsed.Demo,
+		TickRate:    parsed.TickRate,
+		Map:         parsed.Map,
+		Score:       parsed.Score,
+		Perspective: model.PerspectiveKills,
+		Highlights:  highlights,
+	}
+}
+
+// rankHighlights scores every highlight and sorts best first; ties keep match
+// order.
+func rankHighlights(highlights []model.Highlight) {
+	for i := range highlights {
+		highlights[i].Score = scoreHighlight(highlights[i])
+	}
+	slices.SortStableFunc(highlights, func(a, b model.Highlight) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.TickStart, b.TickStart)
+	})
+}
+
+func killerIDs(kills []model.KillEvent) []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0)
+	for _, kill := range kills {
+		if seen[kill.KillerID] {
+			continue
+		}
+		seen[kill.KillerID] = true
+		ids = append(ids, kill.KillerID)
+	}
+	return ids
+}
+
+func filterKills(kills []model.KillEvent, keep func(model.KillEvent) bool) []model.KillEvent {
+	filtered := make([]model.KillEvent, 0)
+	for _, kill := range kills {
+		if keep(kill) {
+			filtered = append(filtered, kill)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,57 @@
+package timeline
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+)
+
+// recordStartSeconds is where the record side of an EDL starts, the usual
+// first hour of an NLE timeline.
+const recordStartSeconds = 3600
+
+// maxEDLRate is the fastest frame rate an EDL is timed at: CMX3600 frame
+// fields hold two digits, so faster recordings are timed at this rate.
+const maxEDLRate = 60
+
+// EDL renders the manifest as a CMX3600 edit list of cuts. An EDL has one
+// video track, so only the first layer of each recording is listed. Each
+// event names its file in a FROM CLIP NAME / SOURCE FILE comment, and kills
+// are locators on the record side. Recordings above maxEDLRate are timed
+// at maxEDLRate, their frames converted.
+func (e *Exporter) EDL(manifest hlae.Manifest) string {
+	rate := frameRate(manifest)
+	fps := min(rate, maxEDLRate)
+	convert := func(frame int) int {
+		return int(math.Round(float64(frame) * float64(fps) / float64(rate)))
+	}
+	recordStart := recordStartSeconds * fps
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "TITLE: %s\n", title(manifest))
+	sb.WriteString("FCM: NON-DROP FRAME\n\n")
+	for i, c := range e.clips(manifest) {
+		record := recordStart + convert(c.Offset)
+		duration := max(recordStart+convert(c.Offset+c.Duration)-record, 1)
+		fmt.Fprintf(&sb, "%03d  AX       V     C        %s %s %s %s\n",
+			i+1, timecode(0, fps), timecode(duration, fps), timecode(record, fps), timecode(record+duration, fps))
+		fmt.Fprintf(&sb, "* FROM CLIP NAME: %s\n", c.Name)
+		if len(c.Media) > 0 {
+			fmt.Fprintf(&sb, "* SOURCE FILE: %s\n", c.Media[0])
+		}
+		for _, m := range c.Markers {
+			fmt.Fprintf(&sb, "* LOC: %s RED     %s\n", timecode(recordStart+convert(c.Offset+m.Frame), fps), m.Name)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// timecode formats a frame count as non-drop HH:MM:SS:FF.
+func timecode(frame, fps int) string {
+	ff := frame % fps
+	seconds := frame / fps
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60, ff)
+}
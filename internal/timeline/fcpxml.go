@@ -0,0 +1,188 @@
+package timeline
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+)
+
+const fcpxmlVersion = "1.9"
+
+type fcpxml struct {
+	XMLName   xml.Name     `xml:"fcpxml"`
+	Version   string       `xml:"version,attr"`
+	Resources fcpResources `xml:"resources"`
+	Library   fcpLibrary   `xml:"library"`
+}
+
+type fcpResources struct {
+	Format fcpFormat  `xml:"format"`
+	Assets []fcpAsset `xml:"asset"`
+}
+
+type fcpFormat struct {
+	ID            string `xml:"id,attr"`
+	FrameDuration string `xml:"frameDuration,attr"`
+	Width         int    `xml:"width,attr"`
+	Height        int    `xml:"height,attr"`
+}
+
+type fcpAsset struct {
+	ID       string      `xml:"id,attr"`
+	Name     string      `xml:"name,attr"`
+	Start    string      `xml:"start,attr"`
+	Duration string      `xml:"duration,attr"`
+	HasVideo int         `xml:"hasVideo,attr"`
+	Format   string      `xml:"format,attr"`
+	MediaRep fcpMediaRep `xml:"media-rep"`
+}
+
+type fcpMediaRep struct {
+	Kind string `xml:"kind,attr"`
+	Src  string `xml:"src,attr"`
+}
+
+type fcpLibrary struct {
+	Event fcpEvent `xml:"event"`
+}
+
+type fcpEvent struct {
+	Name    string     `xml:"name,attr"`
+	Project fcpProject `xml:"project"`
+}
+
+type fcpProject struct {
+	Name     string      `xml:"name,attr"`
+	Sequence fcpSequence `xml:"sequence"`
+}
+
+type fcpSequence struct {
+	Format   string   `xml:"format,attr"`
+	Duration string   `xml:"duration,attr"`
+	TCStart  string   `xml:"tcStart,attr"`
+	TCFormat string   `xml:"tcFormat,attr"`
+	Spine    fcpSpine `xml:"spine"`
+}
+
+type fcpSpine struct {
+	Clips []fcpClip `xml:"asset-clip"`
+}
+
+// fcpClip is an asset clip: on the spine at Offset, or, with a Lane, a
+// connected clip above its parent starting at the parent's Start.
+type fcpClip struct {
+	Ref       string      `xml:"ref,attr"`
+	Lane      int         `xml:"lane,attr,omitempty"`
+	Offset    string      `xml:"offset,attr"`
+	Name      string      `xml:"name,attr"`
+	Start     string      `xml:"start,attr"`
+	Duration  string      `xml:"duration,attr"`
+	Connected []fcpClip   `xml:"asset-clip"`
+	Markers   []fcpMarker `xml:"marker"`
+}
+
+type fcpMarker struct {
+	Start    string `xml:"start,attr"`
+	Duration string `xml:"duration,attr"`
+	Value    string `xml:"value,attr"`
+}
+
+// FCPXML renders the manifest as an FCPXML project with one asset per
+// recorded stream. The first layer of each recording sits on the spine with
+// the kill markers; further layers are connected clips on the lanes above
+// it, in layer order.
+func (e *Exporter) FCPXML(manifest hlae.Manifest) (string, error) {
+	fps := frameRate(manifest)
+	at := func(frame int) string {
+		if frame == 0 {
+			return "0s"
+		}
+		return fmt.Sprintf("%d/%ds", frame, fps)
+	}
+
+	doc := fcpxml{
+		Version: fcpxmlVersion,
+		Resources: fcpResources{
+			Format: fcpFormat{ID: "r0", FrameDuration: at(1), Width: e.Width, Height: e.Height},
+		},
+	}
+	var spine []fcpClip
+	total := 0
+	for _, c := range e.clips(manifest) {
+		var layers []fcpClip
+		for lane, media := range c.Media {
+			asset := fcpAsset{
+				ID:       fmt.Sprintf("r%d", len(doc.Resources.Assets)+1),
+				Name:     c.Name,
+				Start:    "0s",
+				Duration: at(c.Duration),
+				HasVideo: 1,
+				Format:   "r0",
+				MediaRep: fcpMediaRep{Kind: "original-media", Src: fileURL(media)},
+			}
+			if len(c.Media) > 1 {
+				asset.Name = c.Name + " " + layerName(media)
+			}
+			doc.Resources.Assets = append(doc.Resources.Assets, asset)
+			layers = append(layers, fcpClip{Ref: asset.ID, Lane: lane, Offset: "0s", Name: asset.Name, Start: "0s", Duration: at(c.Duration)})
+		}
+		if len(layers) == 0 {
+			continue
+		}
+		primary := layers[0]
+		primary.Offset = at(c.Offset)
+		primary.Connected = layers[1:]
+		for _, m := range c.Markers {
+			primary.Markers = append(primary.Markers, fcpMarker{Start: at(m.Frame), Duration: at(1), Value: m.Name})
+		}
+		spine = append(spine, primary)
+		total = c.Offset + c.Duration
+	}
+
+	name := title(manifest)
+	doc.Library.Event = fcpEvent{Name: name, Project: fcpProject{Name: name, Sequence: fcpSequence{
+		Format:   "r0",
+		Duration: at(total),
+		TCStart:  "0s",
+		TCFormat: "NDF",
+		Spine:    fcpSpine{Clips: spine},
+	}}}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode fcpxml: %w", err)
+	}
+	return xml.Header + "<!DOCTYPE fcpxml>\n" + string(data) + "\n", nil
+}
+
+// layerName names a stream by its folder.
+func layerName(media string) string {
+	parts := strings.Split(media, "/")
+	if len(parts) < 2 {
+		return media
+	}
+	return parts[len(parts)-2]
+}
+
+// fileURL makes a media path a file URL, absolute when the recordings go to
+// an absolute folder and relative to the timeline file otherwise.
+func fileURL(media string) string {
+	slashed := filepath.ToSlash(media)
+	if !strings.HasPrefix(slashed, "/") && !hasDrive(slashed) {
+		return (&url.URL{Path: slashed}).String()
+	}
+	if hasDrive(slashed) {
+		slashed = "/" + slashed
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String()
+}
+
+// hasDrive reports a Windows path like C:/demos, as written on Linux too.
+func hasDrive(p string) bool {
+	return len(p) >= 3 && p[1] == ':' && p[2] == '/' &&
+		(p[0] >= 'a' && p[0] <= 'z' || p[0] >= 'A' && p[0] <= 'Z')
+}
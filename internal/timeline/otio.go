@@ -0,0 +1,143 @@
+package timeline
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+)
+
+// otioTimeline and the types below follow the OpenTimelineIO 0.15 schemas.
+type otioTimeline struct {
+	Schema   string         `json:"OTIO_SCHEMA"`
+	Name     string         `json:"name"`
+	Metadata map[string]any `json:"metadata"`
+	Tracks   otioStack      `json:"tracks"`
+}
+
+type otioStack struct {
+	Schema   string         `json:"OTIO_SCHEMA"`
+	Name     string         `json:"name"`
+	Metadata map[string]any `json:"metadata"`
+	Children []otioTrack    `json:"children"`
+}
+
+type otioTrack struct {
+	Schema   string         `json:"OTIO_SCHEMA"`
+	Name     string         `json:"name"`
+	Kind     string         `json:"kind"`
+	Metadata map[string]any `json:"metadata"`
+	Children []otioClip     `json:"children"`
+}
+
+type otioClip struct {
+	Schema          string                   `json:"OTIO_SCHEMA"`
+	Name            string                   `json:"name"`
+	Metadata        map[string]any           `json:"metadata"`
+	SourceRange     otioRange                `json:"source_range"`
+	MediaReferences map[string]otioReference `json:"media_references"`
+	ActiveMedia     string                   `json:"active_media_reference_key"`
+	Markers         []otioMarker             `json:"markers"`
+}
+
+type otioReference struct {
+	Schema         string         `json:"OTIO_SCHEMA"`
+	Name           string         `json:"name"`
+	Metadata       map[string]any `json:"metadata"`
+	TargetURL      string         `json:"target_url"`
+	AvailableRange otioRange      `json:"available_range"`
+}
+
+type otioMarker struct {
+	Schema      string         `json:"OTIO_SCHEMA"`
+	Name        string         `json:"name"`
+	Metadata    map[string]any `json:"metadata"`
+	Color       string         `json:"color"`
+	MarkedRange otioRange      `json:"marked_range"`
+	Comment     string         `json:"comment"`
+}
+
+type otioRange struct {
+	Schema    string   `json:"OTIO_SCHEMA"`
+	StartTime otioTime `json:"start_time"`
+	Duration  otioTime `json:"duration"`
+}
+
+type otioTime struct {
+	Schema string  `json:"OTIO_SCHEMA"`
+	Rate   float64 `json:"rate"`
+	Value  float64 `json:"value"`
+}
+
+const otioMediaKey = "DEFAULT_MEDIA"
+
+// OTIO renders the manifest as an OpenTimelineIO timeline with a video
+// track per layer, bottom to top in layer order. Kill markers sit on the
+// clips of the first track.
+func (e *Exporter) OTIO(manifest hlae.Manifest) (string, error) {
+	fps := float64(frameRate(manifest))
+	span := func(start, duration int) otioRange {
+		return otioRange{
+			Schema:    "TimeRange.1",
+			StartTime: otioTime{Schema: "RationalTime.1", Rate: fps, Value: float64(start)},
+			Duration:  otioTime{Schema: "RationalTime.1", Rate: fps, Value: float64(duration)},
+		}
+	}
+
+	var tracks []otioTrack
+	for _, c := range e.clips(manifest) {
+		for layer, media := range c.Media {
+			for len(tracks) <= layer {
+				tracks = append(tracks, otioTrack{
+					Schema:   "Track.1",
+					Name:     fmt.Sprintf("V%d", len(tracks)+1),
+					Kind:     "Video",
+					Metadata: map[string]any{},
+					Children: []otioClip{},
+				})
+			}
+			clip := otioClip{
+				Schema:      "Clip.2",
+				Name:        c.Name,
+				Metadata:    map[string]any{},
+				SourceRange: span(0, c.Duration),
+				MediaReferences: map[string]otioReference{otioMediaKey: {
+					Schema:         "ExternalReference.1",
+					Name:           layerName(media),
+					Metadata:       map[string]any{},
+					TargetURL:      fileURL(media),
+					AvailableRange: span(0, c.Duration),
+				}},
+				ActiveMedia: otioMediaKey,
+				Markers:     []otioMarker{},
+			}
+			if layer == 0 {
+				for _, m := range c.Markers {
+					clip.Markers = append(clip.Markers, otioMarker{
+						Schema:      "Marker.2",
+						Name:        m.Name,
+						Metadata:    map[string]any{},
+						Color:       "RED",
+						MarkedRange: span(m.Frame, 0),
+					})
+				}
+			}
+			tracks[layer].Children = append(tracks[layer].Children, clip)
+		}
+	}
+	if tracks == nil {
+		tracks = []otioTrack{}
+	}
+
+	doc := otioTimeline{
+		Schema:   "Timeline.1",
+		Name:     title(manifest),
+		Metadata: map[string]any{},
+		Tracks:   otioStack{Schema: "Stack.1", Name: "tracks", Metadata: map[string]any{}, Children: tracks},
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode otio: %w", err)
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,147 @@
+// Package timeline exports the recordings of a manifest as an editing
+// timeline: a CMX3600 EDL, an FCPXML for Final Cut Pro and DaVinci Resolve,
+// and an OpenTimelineIO file. Clips are laid back to back in recording
+// order, pointing at the files HLAE writes, with a marker at every kill.
+package timeline
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+)
+
+// Format is a timeline file format.
+type Format string
+
+const (
+	FormatEDL    Format = "edl"
+	FormatFCPXML Format = "fcpxml"
+	FormatOTIO   Format = "otio"
+)
+
+func AllFormats() []Format {
+	return []Format{FormatEDL, FormatFCPXML, FormatOTIO}
+}
+
+// Ext is the file extension of the format, with the dot.
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// Exporter renders manifests as timelines. VideoFile is the name of the
+// file HLAE's FFmpeg presets write into each stream folder; Width and
+// Height describe the recordings to editors that need a format.
+type Exporter struct {
+	VideoFile string
+	Width     int
+	Height    int
+}
+
+func NewExporter() *Exporter {
+	return &Exporter{
+		VideoFile: "video.mp4",
+		Width:     1920,
+		Height:    1080,
+	}
+}
+
+// Export renders the manifest in format.
+func (e *Exporter) Export(manifest hlae.Manifest, format Format) (string, error) {
+	switch format {
+	case FormatEDL:
+		return e.EDL(manifest), nil
+	case FormatFCPXML:
+		return e.FCPXML(manifest)
+	case FormatOTIO:
+		return e.OTIO(manifest)
+	}
+	return "", fmt.Errorf("unknown timeline format %q", format)
+}
+
+// Path is where the format's timeline of the script at scriptPath goes,
+// next to its manifest.
+func Path(scriptPath string, format Format) string {
+	return strings.TrimSuffix(scriptPath, filepath.Ext(scriptPath)) + format.Ext()
+}
+
+// clip is one recording on the timeline, in frames. Media holds the video
+// file of every layer, in manifest layer order.
+type clip struct {
+	Name     string
+	Media    []string
+	Offset   int
+	Duration int
+	Markers  []marker
+}
+
+// marker is a kill, in frames from the start of its clip.
+type marker struct {
+	Frame int
+	Name  string
+}
+
+// clips lays the manifest's recordings back to back at its frame rate.
+func (e *Exporter) clips(manifest hlae.Manifest) []clip {
+	fps := frameRate(manifest)
+	clips := make([]clip, 0, len(manifest.Recordings))
+	offset := 0
+	for _, recording := range manifest.Recordings {
+		c := clip{
+			Name:     clipName(recording),
+			Offset:   offset,
+			Duration: max(frames(recording.Duration, fps), 1),
+		}
+		for _, stream := range recording.Streams {
+			c.Media = append(c.Media, stream+"/"+e.VideoFile)
+		}
+		for _, h := range recording.Highlights {
+			for k, at := range h.KillTimes {
+				name := fmt.Sprintf("%s r%d", h.Type, h.Round)
+				if len(h.KillTimes) > 1 {
+					name = fmt.Sprintf("%s kill %d", name, k+1)
+				}
+				c.Markers = append(c.Markers, marker{Frame: min(frames(at, fps), c.Duration-1), Name: name})
+			}
+		}
+		clips = append(clips, c)
+		offset += c.Duration
+	}
+	return clips
+}
+
+// clipName names a recording after its take folder, prefixed with the demo
+// in a chain.
+func clipName(recording hlae.ManifestRecording) string {
+	name := path.Base(recording.Folder)
+	if dir := path.Dir(recording.Folder); name == "take0000" && dir != "." {
+		name = path.Base(dir)
+	}
+	if recording.Demo != "" {
+		demo := path.Base(strings.ReplaceAll(recording.Demo, `\`, "/"))
+		name = strings.TrimSuffix(demo, path.Ext(demo)) + " " + name
+	}
+	return name
+}
+
+func frameRate(manifest hlae.Manifest) int {
+	if manifest.FrameRate > 0 {
+		return manifest.FrameRate
+	}
+	return 60
+}
+
+func frames(seconds float64, fps int) int {
+	return int(math.Round(seconds * float64(fps)))
+}
+
+// title names the timeline after the render target.
+func title(manifest hlae.Manifest) string {
+	if manifest.Target != "" {
+		return manifest.Target
+	}
+	return "highlights"
+}
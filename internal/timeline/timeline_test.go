@@ -0,0 +1,142 @@
+package timeline
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
+	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
+)
+
+func testManifest() hlae.Manifest {
+	return hlae.Manifest{
+		Target:    "clips",
+		Mode:      "clips",
+		TickRate:  64,
+		FrameRate: 60,
+		Layers: []hlae.ManifestLayer{
+			{Name: "world", Kind: hlae.LayerWorld},
+			{Name: "deathmsg", Kind: hlae.LayerDeathmsg},
+		},
+		Recordings: []hlae.ManifestRecording{
+			{
+				Folder:   "/rec/7656/r3_headshot_kill_1000/take0000",
+				Streams:  []string{"/rec/7656/r3_headshot_kill_1000/take0000/world", "/rec/7656/r3_headshot_kill_1000/take0000/deathmsg"},
+				Duration: 5,
+				Highlights: []hlae.ManifestHighlight{
+					{Type: model.HighlightHeadshot, Round: 3, KillTimes: []float64{3}},
+				},
+			},
+			{
+				Folder:   "/rec/7656/r9_round_multikill_5000/take0000",
+				Streams:  []string{"/rec/7656/r9_round_multikill_5000/take0000/world", "/rec/7656/r9_round_multikill_5000/take0000/deathmsg"},
+				Duration: 7.5,
+				Highlights: []hlae.ManifestHighlight{
+					{Type: model.HighlightMultiKill, Round: 9, KillTimes: []float64{3, 4.25}},
+				},
+			},
+		},
+	}
+}
+
+func TestEDLListsClipsWithKillLocators(t *testing.T) {
+	edl := NewExporter().EDL(testManifest())
+	for _, want := range []string{
+		"TITLE: clips\nFCM: NON-DROP FRAME\n\n",
+		"001  AX       V     C        00:00:00:00 00:00:05:00 01:00:00:00 01:00:05:00\n* FROM CLIP NAME: r3_headshot_kill_1000\n* SOURCE FILE: /rec/7656/r3_headshot_kill_1000/take0000/world/video.mp4\n* LOC: 01:00:03:00 RED     headshot_kill r3\n",
+		"002  AX       V     C        00:00:00:00 00:00:07:30 01:00:05:00 01:00:12:30\n",
+		"* LOC: 01:00:08:00 RED     round_multikill r9 kill 1\n* LOC: 01:00:09:15 RED     round_multikill r9 kill 2\n",
+	} {
+		if !strings.Contains(edl, want) {
+			t.Fatalf("expected %q in edl:\n%s", want, edl)
+		}
+	}
+}
+
+func TestEDLTimesFastRecordingsAtSixtyFrames(t *testing.T) {
+	manifest := testManifest()
+	manifest.FrameRate = 240
+	edl := NewExporter().EDL(manifest)
+	for _, want := range []string{
+		"002  AX       V     C        00:00:00:00 00:00:07:30 01:00:05:00 01:00:12:30\n",
+		"* LOC: 01:00:09:15 RED     round_multikill r9 kill 2\n",
+	} {
+		if !strings.Contains(edl, want) {
+			t.Fatalf("expected %q in edl:\n%s", want, edl)
+		}
+	}
+	for _, line := range strings.Split(edl, "\n") {
+		for _, field := range strings.Fields(line) {
+			if parts := strings.Split(field, ":"); len(parts) == 4 && len(parts[3]) != 2 {
+				t.Fatalf("expected two-digit frame fields, got %q in:\n%s", field, edl)
+			}
+		}
+	}
+}
+
+func TestFCPXMLConnectsLayersAndMarksKills(t *testing.T) {
+	out, err := NewExporter().FCPXML(testManifest())
+	if err != nil {
+		t.Fatalf("fcpxml: %v", err)
+	}
+	var doc fcpxml
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("decode fcpxml: %v\n%s", err, out)
+	}
+	if len(doc.Resources.Assets) != 4 || doc.Resources.Assets[0].MediaRep.Src != "file:///rec/7656/r3_headshot_kill_1000/take0000/world/video.mp4" {
+		t.Fatalf("expected an asset per stream, got %+v", doc.Resources.Assets)
+	}
+	spine := doc.Library.Event.Project.Sequence.Spine.Clips
+	if len(spine) != 2 || spine[1].Offset != "300/60s" || spine[1].Duration != "450/60s" {
+		t.Fatalf("expected two clips back to back, got %+v", spine)
+	}
+	if len(spine[1].Connected) != 1 || spine[1].Connected[0].Lane != 1 || spine[1].Connected[0].Ref != "r4" {
+		t.Fatalf("expected the deathmsg layer connected above, got %+v", spine[1].Connected)
+	}
+	if len(spine[1].Markers) != 2 || spine[1].Markers[1].Start != "255/60s" || spine[1].Markers[1].Value != "round_multikill r9 kill 2" {
+		t.Fatalf("expected kill markers, got %+v", spine[1].Markers)
+	}
+	if doc.Library.Event.Project.Sequence.Duration != "750/60s" {
+		t.Fatalf("expected the sequence to end after the last clip, got %s", doc.Library.Event.Project.Sequence.Duration)
+	}
+}
+
+func TestOTIOPutsLayersOnTracks(t *testing.T) {
+	out, err := NewExporter().OTIO(testManifest())
+	if err != nil {
+		t.Fatalf("otio: %v", err)
+	}
+	var doc otioTimeline
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("decode otio: %v", err)
+	}
+	tracks := doc.Tracks.Children
+	if len(tracks) != 2 || tracks[1].Name != "V2" || len(tracks[0].Children) != 2 {
+		t.Fatalf("expected a track per layer, got %+v", tracks)
+	}
+	clip := tracks[0].Children[1]
+	if clip.SourceRange.Duration.Value != 450 || clip.MediaReferences[otioMediaKey].TargetURL != "file:///rec/7656/r9_round_multikill_5000/take0000/world/video.mp4" {
+		t.Fatalf("unexpected clip %+v", clip)
+	}
+	if len(clip.Markers) != 2 || clip.Markers[0].MarkedRange.StartTime.Value != 180 {
+		t.Fatalf("expected kill markers on the first track, got %+v", clip.Markers)
+	}
+	if len(tracks[1].Children[0].Markers) != 0 {
+		t.Fatalf("expected no markers on upper tracks")
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	cases := map[string]string{
+		"/rec/a b/video.mp4":        "file:///rec/a%20b/video.mp4",
+		"C:/rec/video.mp4":          "file:///C:/rec/video.mp4",
+		"take0000/screen/video.mp4": "take0000/screen/video.mp4",
+	}
+	for in, want := range cases {
+		if got := fileURL(in); got != want {
+			t.Fatalf("fileURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
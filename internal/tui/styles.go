@@ -25,6 +25,7 @@ var (
 	selectedStyle = lipgloss.NewStyle().Foreground(accent).Bold(true)
 	activeTab     = lipgloss.NewStyle().Foreground(light).Background(accentDeep).Padding(0, 1)
 	inactiveTab   = lipgloss.NewStyle().Foreground(subtle).Padding(0, 1)
+	statsBoxStyle = lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(subtle).Padding(0, 1)
 
 	spinnerStyle     = lipgloss.NewStyle().Foreground(accent)
 	teamLabelStyle   = lipgloss.NewStyle().Bold(true).Foreground(accent)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/filepicker"
 	"github.com/charmbracelet/bubbles/progress"
@@ -14,6 +15,7 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/eSheikh/cs2-demo-highlighter/internal/atomicfile"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/engine"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/hlae"
 	"github.com/eSheikh/cs2-demo-highlighter/internal/model"
@@ -411,26 +413,26 @@ func (m appModel) generate(mode hlae.Mode, name string) string {
 		return errStyle.Render("select at least one highlight type")
 	}
 	if name == "" {
-		name = modeName(mode)
+		name = mode.String()
 	}
 	path := name + ".cfg"
-	content := hlae.BuildTarget(m.result, m.options, hlae.Target{
+	files, err := hlae.BuildTarget(m.result, m.options, hlae.Target{
 		Mode:  mode,
 		Types: selection,
 		Path:  path,
 		Name:  name,
 	})
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
-		return errStyle.Render("write failed: " + err.Error())
+	if err != nil {
+		return errStyle.Render("generate failed: " + err.Error())
 	}
-	return okStyle.Render(fmt.Sprintf("saved %s", path))
-}
-
-func modeName(mode hlae.Mode) string {
-	if mode == hlae.ModeMontage {
-		return "montage"
+	saved := make([]string, 0, len(files))
+	for _, file := range files {
+		if err := atomicfile.WriteFile(file.Path, []byte(file.Content), 0o644); err != nil {
+			return errStyle.Render("write failed: " + err.Error())
+		}
+		saved = append(saved, file.Path)
 	}
-	return "clips"
+	return okStyle.Render(fmt.Sprintf("saved %s", strings.Join(saved, ", ")))
 }
 
 func countTypes(result model.HighlightResult) []typeCount {
@@ -536,6 +538,9 @@ func (m appModel) bodyParsing() string {
 func (m appModel) bodyResults() string {
 	s := titleStyle.Render("Highlights") + "  "
 	s += dimStyle.Render(fmt.Sprintf("%s — %d total", m.result.Demo, len(m.result.Highlights))) + "\n\n"
+	if m.result.Stats != nil {
+		s += statsPanel(m.result.Stats.Totals) + "\n\n"
+	}
 
 	if len(m.types) == 0 {
 		s += dimStyle.Render("no highlights found") + "\n"
@@ -568,6 +573,25 @@ func (m appModel) bodyResults() string {
 	return s
 }
 
+// statsPanel renders the player's match totals as two compact lines.
+func statsPanel(t model.StatsTotals) string {
+	stat := func(label, value string) string {
+		return dimStyle.Render(label+" ") + value
+	}
+	first := []string{
+		stat("K/D/A", fmt.Sprintf("%d/%d/%d", t.Kills, t.Deaths, t.Assists)),
+		stat("ADR", fmt.Sprintf("%.1f", t.ADR)),
+		stat("HS", fmt.Sprintf("%.0f%%", t.HSPercent)),
+		stat("KAST", fmt.Sprintf("%.0f%%", t.KASTPercent)),
+	}
+	second := []string{
+		stat("Opening", fmt.Sprintf("%d/%d", t.OpeningKills, t.OpeningDeaths)),
+		stat("Clutches", fmt.Sprintf("%d/%d", t.ClutchesWon, t.ClutchesAttempted)),
+		stat("Rounds", fmt.Sprintf("%d", t.Rounds)),
+	}
+	return statsBoxStyle.Render(strings.Join(first, "   ") + "\n" + strings.Join(second, "   "))
+}
+
 func (m appModel) resultsFooter() string {
 	if m.resultsFocus == focusOutput {
 		return "type name   enter generate   tab/esc back   ctrl+c quit"
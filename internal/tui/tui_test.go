@@ -3,6 +3,7 @@ package tui
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -168,3 +169,18 @@ func TestGenerateWritesCfg(t *testing.T) {
 		t.Fatalf("clips.cfg is empty")
 	}
 }
+
+func TestResultsShowStatsPanel(t *testing.T) {
+	result := sampleResult()
+	result.Stats = &model.PlayerStats{
+		Totals: model.StatsTotals{Rounds: 24, Kills: 21, Deaths: 15, Assists: 4, ADR: 84.2, HSPercent: 47.6, KASTPercent: 72, ClutchesWon: 1, ClutchesAttempted: 3},
+	}
+	m := appModel{state: stateResults, result: result, types: countTypes(result)}
+
+	body := m.bodyResults()
+	for _, want := range []string{"21/15/4", "84.2", "72%", "1/3"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected stats panel to contain %q, got:\n%s", want, body)
+		}
+	}
+}